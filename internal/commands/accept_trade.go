@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandAcceptTrade accepts a trade offered to the user
+type CommandAcceptTrade struct{}
+
+func (c *CommandAcceptTrade) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "accept",
+		Description: "Accept a trade offered to you",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "trade_id",
+				Description:  "The ID of the trade to accept",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandAcceptTrade) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := commandOptions(i)
+		if len(options) != 1 {
+			log.WithField("cmd", "/accept").Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true, "Invalid trade", "You need to supply the ID of the trade to accept.")
+			return
+		}
+
+		acceptTrade(s, i, state, "cmd", "/accept", options[0].StringValue())
+	}
+}
+
+func (c *CommandAcceptTrade) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAcceptTrade) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAcceptTrade) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"trade_id": autocompletePendingTrades(state, true),
+	}
+}
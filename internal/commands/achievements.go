@@ -0,0 +1,132 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandAchievements shows the user's progress on the achievements
+type CommandAchievements struct{}
+
+func (c *CommandAchievements) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "achievements",
+		Description: "Show your progress on the achievements",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "id",
+				Description: "The achievement to show the levels of",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    false,
+				Choices:     achievementChoices(),
+			},
+		},
+	}
+}
+
+func (c *CommandAchievements) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/achievements").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		id := ""
+		for _, opt := range commandOptions(i) {
+			if opt.Name == "id" {
+				id = opt.StringValue()
+			}
+		}
+
+		progress, err := models.GetAchievementProgress(state.DB, user.DiscordID)
+		if err != nil {
+			log.WithField("cmd", "/achievements").WithError(err).Warnf("Error getting achievements for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with getting your achievements, please try again.",
+			)
+			return
+		}
+
+		// Without an achievement picked, show the progress on all of them
+		if id == "" {
+			body := "```\n"
+			for _, achievement := range models.Achievements {
+				entry := progress[achievement.ID]
+				next := "done"
+				if entry.Level < models.AchievementLevels {
+					next = fmt.Sprintf("%d/%d", entry.Progress, achievement.Thresholds[entry.Level])
+				}
+				body += fmt.Sprintf("%s %-19s %s %s\n", achievement.Symbol, achievement.Name, models.RenderAchievementLevel(entry.Level), next)
+			}
+			body += "```\nPick an achievement with `id` to see what each level takes."
+
+			ResponseEmbedInfo(s, i, true, fmt.Sprintf("%s's Achievements", i.Member.User.Username), body)
+			return
+		}
+
+		achievement, ok := models.GetAchievement(models.AchievementID(id))
+		if !ok {
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("Achievement %s not avaliable", id), "There is no achievement with the ID you supplied.")
+			return
+		}
+
+		entry := progress[achievement.ID]
+		body := fmt.Sprintf("**Progress**: %d `%s`\n\n", entry.Progress, models.RenderAchievementLevel(entry.Level))
+		for level, flavour := range achievement.Flavour {
+			check := "⬛"
+			if level < entry.Level {
+				check = "✅"
+			}
+			body += fmt.Sprintf("%s **Level %d** (%d): %s\n", check, level+1, achievement.Thresholds[level], flavour)
+		}
+
+		ResponseEmbedInfo(s, i, true, fmt.Sprintf("%s %s", achievement.Symbol, achievement.Name), body)
+	}
+}
+
+// The achievements as choices for a command option
+func achievementChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, achievement := range models.Achievements {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  achievement.Name,
+			Value: string(achievement.ID),
+		})
+	}
+	return choices
+}
+
+// Render the badges the user has unlocked, for their profile
+func renderBadges(progress map[models.AchievementID]models.AchievementProgress) string {
+	badges := ""
+	for _, achievement := range models.Achievements {
+		if entry := progress[achievement.ID]; entry.Level > 0 {
+			badges += fmt.Sprintf("%s %-19s %s\n", achievement.Symbol, achievement.Name, models.RenderAchievementLevel(entry.Level))
+		}
+	}
+	return badges
+}
+
+func (c *CommandAchievements) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAchievements) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAchievements) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
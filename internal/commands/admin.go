@@ -0,0 +1,331 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Admins are configured as comma separated Discord IDs, either of the
+	// users themselves or of roles that they have.
+	AdminUsersEnv = "ADMIN_USERS"
+	AdminRolesEnv = "ADMIN_ROLES"
+
+	AdminAuditLimit = 15
+)
+
+// CommandAdmin is the group of commands only admins can use
+type CommandAdmin struct{}
+
+// Whether the user that sent the interaction is an admin
+func isAdmin(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil || i.Member.User == nil {
+		return false
+	}
+
+	for _, id := range strings.Split(os.Getenv(AdminUsersEnv), ",") {
+		if strings.TrimSpace(id) == i.Member.User.ID {
+			return true
+		}
+	}
+	for _, id := range strings.Split(os.Getenv(AdminRolesEnv), ",") {
+		for _, role := range i.Member.Roles {
+			if strings.TrimSpace(id) == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether the user that sent the interaction can manage the guild, guild
+// managers can change the guild's own settings without being an admin.
+func isGuildManager(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil || i.GuildID == "" {
+		return false
+	}
+	return i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+}
+
+func (c *CommandAdmin) Decleration() *discordgo.ApplicationCommandOption {
+	userOption := &discordgo.ApplicationCommandOption{
+		Name:        "user",
+		Description: "The user to apply it to",
+		Type:        discordgo.ApplicationCommandOptionUser,
+		Required:    true,
+	}
+	achievementOption := &discordgo.ApplicationCommandOption{
+		Name:        "achievement",
+		Description: "The achievement, defaults to Snail Mechanic",
+		Type:        discordgo.ApplicationCommandOptionString,
+		Required:    false,
+		Choices:     achievementChoices(),
+	}
+
+	levels := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, level := range []models.SnailStatLevel{models.StartingSnail, models.AmateurSnail, models.ProfessionalSnail, models.ExpertSnail, models.RandomSnail} {
+		levels = append(levels, &discordgo.ApplicationCommandOptionChoice{Name: level.Title(), Value: int(level)})
+	}
+
+	return &discordgo.ApplicationCommandOption{
+		Name:        "admin",
+		Description: "Admin only commands",
+		Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "grant",
+				Description: "Grant a user progress on an achievement",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{userOption, achievementOption},
+			},
+			{
+				Name:        "revoke",
+				Description: "Revoke progress on an achievement from a user",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     []*discordgo.ApplicationCommandOption{userOption, achievementOption},
+			},
+			{
+				Name:        "money",
+				Description: "Give money to or take money from a user",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					userOption,
+					{
+						Name:        "amount",
+						Description: "The money to give, negative to take",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Required:    true,
+					},
+					{
+						Name:        "reason",
+						Description: "Why the balance is being changed",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+					},
+				},
+			},
+			{
+				Name:        "cancel",
+				Description: "Cancel a running race and refund its bets",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "race_id",
+						Description: "The ID of the race to cancel",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+					},
+					{
+						Name:        "reason",
+						Description: "Why the race is being cancelled",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    false,
+					},
+				},
+			},
+			{
+				Name:        "spawn",
+				Description: "Spawn a new snail for a user",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					userOption,
+					{
+						Name:        "level",
+						Description: "The level of snail to spawn",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Required:    true,
+						Choices:     levels,
+					},
+				},
+			},
+			{
+				Name:        "audit",
+				Description: "Show the most recent admin actions",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "economy",
+				Description: "Pick whether this server shares wallets and snails with every server",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "economy",
+						Description: "The economy this server plays in",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "Global, shared with every server", Value: string(models.EconomyGlobal)},
+							{Name: "Server, only for this server", Value: string(models.EconomyGuild)},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *CommandAdmin) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		sub := i.ApplicationCommandData().Options[0].Options[0]
+		cmd := fmt.Sprintf("/admin %s", sub.Name)
+
+		// Guild managers can also pick their guild's economy
+		if !isAdmin(i) && !(sub.Name == "economy" && isGuildManager(i)) {
+			log.WithField("cmd", cmd).Warnf("User %s tried to use an admin command", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you aren't an admin", i.Member.User.Username),
+				"Only admins can use this command.",
+			)
+			return
+		}
+
+		// Gather the options of the subcommand
+		var (
+			target         *discordgo.User
+			achievement    = models.AchievementSnailMechanic
+			amount, level  int64
+			reason, raceId string
+			economy        models.Economy
+		)
+		for _, opt := range sub.Options {
+			switch opt.Name {
+			case "user":
+				target = opt.UserValue(s)
+			case "achievement":
+				achievement = models.AchievementID(opt.StringValue())
+			case "amount":
+				amount = opt.IntValue()
+			case "level":
+				level = opt.IntValue()
+			case "reason":
+				reason = opt.StringValue()
+			case "race_id":
+				raceId = opt.StringValue()
+			case "economy":
+				economy, _ = models.ParseEconomy(opt.StringValue())
+			}
+		}
+
+		// Everything but cancelling a race and the audit log is done to a user
+		var user *models.User
+		if target != nil {
+			var err error
+			if user, err = models.GetUserByDiscordID(state.DB, memberAccountID(state, i, target.ID)); err != nil {
+				log.WithField("cmd", cmd).WithError(err).Infof("User %s is not initialised", target.Username)
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry, but %s is not initialised", target.Username),
+					"They will need to initialise an account with `/snailrace init`",
+				)
+				return
+			}
+		}
+
+		switch sub.Name {
+		case "grant":
+			unlocks, err := models.GrantAchievement(state.DB, i.Member.User.ID, user.DiscordID, achievement)
+			if err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+			models.AnnounceAchievements(s, i.ChannelID, unlocks)
+			ResponseEmbedSuccess(s, i, true, "Achievement granted", fmt.Sprintf("Granted %s progress on `%s`.", target.Username, achievement))
+
+		case "revoke":
+			if err := models.RevokeAchievement(state.DB, i.Member.User.ID, user.DiscordID, achievement); err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+			ResponseEmbedSuccess(s, i, true, "Achievement revoked", fmt.Sprintf("Revoked progress on `%s` from %s.", achievement, target.Username))
+
+		case "money":
+			entry, err := models.AdjustBalance(state.DB, i.Member.User.ID, user.DiscordID, amount, reason)
+			if err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+			ResponseEmbedSuccess(s, i, true, "Balance adjusted", fmt.Sprintf("Changed %s's balance by %+dg, they now have %dg.", target.Username, amount, entry.Balance))
+
+		case "cancel":
+			race, ok := state.GetAnyRace(raceId)
+			if !ok {
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is no running race with the ID you supplied.")
+				return
+			}
+			if reason == "" {
+				reason = "An admin has cancelled the race."
+			}
+			if err := models.RecordAdminAction(state.DB, i.Member.User.ID, "cancel", raceId, reason); err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+			race.Cancel(reason)
+			ResponseEmbedSuccess(s, i, true, "Race cancelled", fmt.Sprintf("Race `%s` has been cancelled, any bets on it will be refunded.", raceId))
+
+		case "spawn":
+			snail, err := models.SpawnSnail(state.DB, i.Member.User.ID, *user, models.SnailStatLevel(level))
+			if err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+			ResponseEmbedSuccess(s, i, true, "Snail spawned", fmt.Sprintf("Gave %s **%s** with the following stats:\n```\n%s```", target.Username, snail.Name, snail.Stats.RenderStatBlock()))
+
+		case "audit":
+			actions, err := models.GetRecentAdminActions(state.DB, AdminAuditLimit)
+			if err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+
+			body := ""
+			for _, action := range actions {
+				body += fmt.Sprintf("<t:%d:R> <@%s> **%s** `%s`: %s\n", action.CreatedAt.Unix(), action.AdminID, action.Action, action.TargetID, action.Details)
+			}
+			if body == "" {
+				body = "Nothing has been done yet."
+			}
+			ResponseEmbedInfo(s, i, true, "Admin Audit Log", body)
+
+		case "economy":
+			// Bets are paid to the accounts they were placed from, but races
+			// still running would mix both economies in the one race
+			if state.GuildRacing(i.GuildID) {
+				ResponseEmbedFail(s, i, true, "Races are running", "The economy can't be changed while there are races running in this server, please try again once they are finished.")
+				return
+			}
+			if err := models.SetGuildEconomy(state.DB, i.Member.User.ID, i.GuildID, economy); err != nil {
+				adminFailed(s, i, cmd, err)
+				return
+			}
+
+			body := "Everyone in this server now uses their global wallet and snails."
+			if economy == models.EconomyGuild {
+				body = "Everyone in this server now has a wallet and snails that only exist here, use `/snailrace init` to get started."
+			}
+			ResponseEmbedSuccess(s, i, false, fmt.Sprintf("This server now uses the %s economy", economy.Title()), body)
+		}
+
+		log.WithField("cmd", cmd).Infof("Admin %s used %s", i.Member.User.Username, sub.Name)
+	}
+}
+
+func adminFailed(s *discordgo.Session, i *discordgo.InteractionCreate, cmd string, err error) {
+	log.WithField("cmd", cmd).WithError(err).Warnf("Admin command failed for %s", i.Member.User.Username)
+	ResponseEmbedFail(s, i, true, "Admin command failed", fmt.Sprintf("Nothing has been changed: %s", err))
+}
+
+func (c *CommandAdmin) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAdmin) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAdmin) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
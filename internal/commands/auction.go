@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandAuction puts one of the user's snails up for auction
+type CommandAuction struct{}
+
+func (c *CommandAuction) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "auction",
+		Description: "Put one of your snails up for auction",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail",
+				Description:  "The snail to auction",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Name:        "money",
+				Description: "The starting price",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *CommandAuction) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/auction").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		query, price := "", int64(0)
+		for _, opt := range commandOptions(i) {
+			switch opt.Name {
+			case "snail":
+				query = opt.StringValue()
+			case "money":
+				price = opt.IntValue()
+			}
+		}
+
+		snail, err := models.FindOwnedSnail(state.DB, *user, query)
+		if err != nil {
+			log.WithField("cmd", "/auction").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, query)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+				"You can only auction your own snails.",
+			)
+			return
+		}
+
+		// Snails can't be sold mid race, or join one while they're being put
+		// into escrow
+		var auction *models.Auction
+		err = state.WhileIdle(func() (err error) {
+			auction, err = models.CreateAuction(state.DB, *user, *snail, price)
+			return err
+		}, snail.ID)
+		switch err {
+		case nil:
+		case models.ErrSnailRacing:
+			log.WithField("cmd", "/auction").Infof("User %s tried to auction racing snail %s", i.Member.User.Username, snail.Name)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but %s is racing", i.Member.User.Username, snail.Name),
+				"Snails can't be auctioned while they are in a race, try again once the race is over.",
+			)
+			return
+		case models.ErrInvalidAmount:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that isn't a price", i.Member.User.Username), "The starting price has to be at least 1g.")
+			return
+		case models.ErrLastSnail:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you need a snail", i.Member.User.Username), "You can't auction your last snail.")
+			return
+		case models.ErrSnailInEscrow:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but %s is busy", i.Member.User.Username, snail.Name), "The snail is already being held for a trade or auction.")
+			return
+		default:
+			log.WithField("cmd", "/auction").WithError(err).Warnf("Error creating auction for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with creating your auction, please try again.",
+			)
+			return
+		}
+
+		if err := models.PostAuction(s, state.DB, auction, i.ChannelID); err != nil {
+			log.WithField("cmd", "/auction").WithError(err).Warnf("Failed to post auction %s", auction.AuctionID)
+		}
+
+		log.WithField("cmd", "/auction").Infof("User %s put %s up for auction %s", i.Member.User.Username, snail.Name, auction.AuctionID)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("Auction `%s` started", auction.AuctionID),
+			fmt.Sprintf("**%s** is up for auction starting at %dg. It will be held until the auction ends, which is %d minutes without a bid or %d minute after the last bid.", snail.Name, price, int(models.AuctionTimeout.Minutes()), int(models.AuctionBidTimeout.Minutes())),
+		)
+	}
+}
+
+func (c *CommandAuction) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAuction) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandAuction) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
@@ -0,0 +1,212 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	BackpackActionPage   = "backpack_page"
+	BackpackActionSort   = "backpack_sort"
+	BackpackActionFilter = "backpack_filter"
+)
+
+// CommandBackpack lists all of the user's snails a page at a time
+type CommandBackpack struct{}
+
+func (c *CommandBackpack) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "backpack",
+		Description: "List all of your snails and your money",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+	}
+}
+
+func (c *CommandBackpack) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		c.respond(s, i, state, "cmd", "/backpack", discordgo.InteractionResponseChannelMessageWithSource, models.BackpackSortLevel, models.BackpackFilterAll, 0)
+	}
+}
+
+// Show a page of the user's backpack, this is shared between the command and
+// the buttons and menus on the backpack which update the message in place.
+func (c *CommandBackpack) respond(s *discordgo.Session, i *discordgo.InteractionCreate, state *models.State, field string, name string, responseType discordgo.InteractionResponseType, sort models.BackpackSort, filter models.BackpackFilter, page int) {
+	// Check if the user is initialised, if the user isn't initialised then
+	// we need to tell them to initialise their account.
+	user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+	if err != nil {
+		log.WithField(field, name).WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+			"You'll need to initialise your account with `/snailrace init` to use this command.",
+		)
+		return
+	}
+
+	snails, total, err := models.GetBackpackPage(state.DB, *user, sort, filter, page)
+	if err != nil {
+		log.WithField(field, name).WithError(err).Warnf("Error getting the backpack for user %s", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+			"There has been an issue with opening your backpack, please try again.",
+		)
+		return
+	}
+
+	// Snails could have left the backpack since the last page was shown
+	pages := int((total + models.BackpackPageSize - 1) / models.BackpackPageSize)
+	if pages > 0 && page >= pages {
+		c.respond(s, i, state, field, name, responseType, sort, filter, pages-1)
+		return
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: responseType,
+		Data: backpackResponse(user, snails, total, sort, filter, page, pages),
+	})
+}
+
+// The ephemeral backpack message for a page of snails
+func backpackResponse(user *models.User, snails []models.Snail, total int64, sort models.BackpackSort, filter models.BackpackFilter, page int, pages int) *discordgo.InteractionResponseData {
+	body := fmt.Sprintf("💰 **%dg**\n\n", user.Money)
+	for _, snail := range snails {
+		winRate := uint64(0)
+		if snail.Races > 0 {
+			winRate = snail.Wins * 100 / snail.Races
+		}
+
+		status := ""
+		if snail.Active {
+			status = " ⭐"
+		}
+		if snail.InEscrow() {
+			status += " 🔒"
+		}
+
+		body += fmt.Sprintf(
+			"🐌 **%s**%s (lvl. %d)\n%s\n%s · %d wins from %d races (%d%%)\n```\n%s```\n",
+			snail.Name, status, snail.Level, GenerateProgressBar(snail.LevelProgress()),
+			snail.RenderMood(), snail.Wins, snail.Races, winRate, snail.Stats.RenderStatBlock(),
+		)
+	}
+	if len(snails) == 0 {
+		body += "There are no snails in your backpack that match the filter."
+	}
+
+	sortOptions := make([]discordgo.SelectMenuOption, 0)
+	for _, option := range models.BackpackSorts {
+		sortOptions = append(sortOptions, discordgo.SelectMenuOption{
+			Label:   option.Title(),
+			Value:   string(option),
+			Default: option == sort,
+		})
+	}
+	filterOptions := make([]discordgo.SelectMenuOption, 0)
+	for _, option := range models.BackpackFilters {
+		filterOptions = append(filterOptions, discordgo.SelectMenuOption{
+			Label:   option.Title(),
+			Value:   string(option),
+			Default: option == filter,
+		})
+	}
+
+	footer := fmt.Sprintf("Page %d of %d · %d snails · ⭐ racer 🔒 held", page+1, pages, total)
+	if pages == 0 {
+		footer = "Page 1 of 1 · 0 snails"
+	}
+
+	return &discordgo.InteractionResponseData{
+		Flags: discordgo.MessageFlagsEphemeral,
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Backpack",
+				Color:       0x3498db,
+				Description: body,
+				Footer:      &discordgo.MessageEmbedFooter{Text: footer},
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						MenuType: discordgo.StringSelectMenu,
+						CustomID: fmt.Sprintf("%s:%s", BackpackActionSort, filter),
+						Options:  sortOptions,
+					},
+				},
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						MenuType: discordgo.StringSelectMenu,
+						CustomID: fmt.Sprintf("%s:%s", BackpackActionFilter, sort),
+						Options:  filterOptions,
+					},
+				},
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "Previous",
+						Style:    discordgo.SecondaryButton,
+						CustomID: fmt.Sprintf("%s:%s:%s:%d", BackpackActionPage, sort, filter, page-1),
+						Disabled: page <= 0,
+					},
+					discordgo.Button{
+						Label:    "Next",
+						Style:    discordgo.SecondaryButton,
+						CustomID: fmt.Sprintf("%s:%s:%s:%d", BackpackActionPage, sort, filter, page+1),
+						Disabled: page+1 >= pages,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (c *CommandBackpack) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		BackpackActionPage: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if len(options) != 3 {
+				log.WithField("interaction", BackpackActionPage).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, "Invalid page", "We couldn't work out which page that was, please try again.")
+				return
+			}
+			page, err := strconv.Atoi(options[2])
+			if err != nil || page < 0 {
+				page = 0
+			}
+			c.respond(s, i, state, "interaction", BackpackActionPage, discordgo.InteractionResponseUpdateMessage, models.BackpackSort(options[0]), models.BackpackFilter(options[1]), page)
+		},
+		BackpackActionSort: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if len(options) != 1 {
+				log.WithField("interaction", BackpackActionSort).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, "Invalid sort", "We couldn't work out how to sort your backpack, please try again.")
+				return
+			}
+			sort := models.BackpackSort(i.MessageComponentData().Values[0])
+			c.respond(s, i, state, "interaction", BackpackActionSort, discordgo.InteractionResponseUpdateMessage, sort, models.BackpackFilter(options[0]), 0)
+		},
+		BackpackActionFilter: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if len(options) != 1 {
+				log.WithField("interaction", BackpackActionFilter).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, "Invalid filter", "We couldn't work out how to filter your backpack, please try again.")
+				return
+			}
+			filter := models.BackpackFilter(i.MessageComponentData().Values[0])
+			c.respond(s, i, state, "interaction", BackpackActionFilter, discordgo.InteractionResponseUpdateMessage, models.BackpackSort(options[0]), filter, 0)
+		},
+	}
+}
+
+func (c *CommandBackpack) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBackpack) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
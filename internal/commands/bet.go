@@ -3,6 +3,7 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/lcox74/snailrace/internal/models"
@@ -12,6 +13,14 @@ import (
 type BetCommand struct{}
 
 func (c *BetCommand) Decleration() *discordgo.ApplicationCommandOption {
+	betTypes := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, betType := range models.BetTypes {
+		betTypes = append(betTypes, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s: %s", betType.Title(), betType.Description()),
+			Value: string(betType),
+		})
+	}
+
 	return &discordgo.ApplicationCommandOption{
 		Name:        "bet",
 		Description: "So you want to put your money where your mouth is?",
@@ -25,7 +34,7 @@ func (c *BetCommand) Decleration() *discordgo.ApplicationCommandOption {
 			},
 			{
 				Name:        "snail_index",
-				Description: "The index of the snail in the race.",
+				Description: "The index of the snail in the race, or the 1st place pick for exacta and trifecta bets.",
 				Type:        discordgo.ApplicationCommandOptionInteger,
 				Required:    true,
 			},
@@ -35,6 +44,22 @@ func (c *BetCommand) Decleration() *discordgo.ApplicationCommandOption {
 				Type:        discordgo.ApplicationCommandOptionInteger,
 				Required:    true,
 			},
+			{
+				Name:        "type",
+				Description: "The type of bet, defaults to win.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     betTypes,
+			},
+			{
+				Name:        "second_index",
+				Description: "The 2nd place pick for exacta and trifecta bets.",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+			},
+			{
+				Name:        "third_index",
+				Description: "The 3rd place pick for trifecta bets.",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+			},
 		},
 	}
 }
@@ -43,7 +68,7 @@ func (c *BetCommand) AppHandler(state *models.State) func(s *discordgo.Session,
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		// Check if the user is initialised, if the user isn't initialised then
 		// we need to tell them to initialise their account.
-		user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 		if err != nil {
 			log.WithField("cmd", "/bet").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
 			ResponseEmbedFail(s, i, true,
@@ -55,68 +80,286 @@ func (c *BetCommand) AppHandler(state *models.State) func(s *discordgo.Session,
 
 		// Pull the options from the interaction
 		raceId := ""
-		snailIndex := 0
+		betType := models.BetWin
+		picks := make([]int, 3)
+		picked := make([]bool, 3)
 		amount := 0
-		for _, option := range i.ApplicationCommandData().Options[0].Options {
+		for _, option := range commandOptions(i) {
 			switch option.Name {
 			case "race_id":
 				raceId = option.StringValue()
 			case "snail_index":
-				snailIndex = int(option.IntValue())
+				picks[0], picked[0] = int(option.IntValue()), true
+			case "second_index":
+				picks[1], picked[1] = int(option.IntValue()), true
+			case "third_index":
+				picks[2], picked[2] = int(option.IntValue()), true
 			case "amount":
 				amount = int(option.IntValue())
+			case "type":
+				betType, _ = models.ParseBetType(option.StringValue())
 			}
 		}
 
 		// Check if the race exists, if it doesn't then we need to tell the
 		// user
-		race, ok := state.Races[raceId]
+		race, ok := state.GetRace(i.GuildID, i.ChannelID, raceId)
 		if !ok {
 			log.WithField("cmd", "/bet").WithError(errors.New("race not active")).Infof("User %s tying to bet on a inactive race", i.Member.User.Username)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race with the ID you supplied.")
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race in this channel with the ID you supplied.")
 			return
 		}
 
-		// Check if the snail exists, if it doesn't then we need to tell the
-		// user
-		snail := race.GetSnail(snailIndex)
-		if snail == nil {
-			log.WithField("cmd", "/bet").WithError(errors.New("invalid snail")).Infof("User %s tying to bet invalid snail", i.Member.User.Username)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Invalid snail to bet for race %s", raceId), "There is currently no snail with the ID you supplied.")
+		// Exotic bets need every place picked, each with a different snail
+		if !validPicks(picks[:betType.Picks()], picked[:betType.Picks()]) {
+			log.WithField("cmd", "/bet").WithError(models.ErrInvalidSnail).Infof("User %s placing a %s bet without %d different picks", i.Member.User.Username, betType, betType.Picks())
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s that bet needs more snails", i.Member.User.Username), fmt.Sprintf("A %s bet needs %d different snails from the race, pick one for each place with `snail_index`, `second_index` and `third_index`.", betType, betType.Picks()))
 			return
 		}
 
-		// Check if the user has enough money to make the bet
-		if int(user.Money) < amount {
-			log.WithField("cmd", "/bet").WithError(errors.New("not enough funds")).Infof("User %s doesn't have the funds to place bet", i.Member.User.Username)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s but you can't afford the bet", i.Member.User.Username), fmt.Sprintf("You don't have enough money to place that bet, you only have %d g.", user.Money))
-			return
-		}
+		placeBet(s, i, "cmd", "/bet", race, user, betType, picks[:betType.Picks()], amount)
+	}
+}
 
-		// Place the bet and remove the money from the user
-		switch race.PlaceBet(snailIndex, amount, user.DiscordID) {
-		case models.ErrInvalidSnail:
-			log.WithField("cmd", "/bet").WithError(models.ErrInvalidSnail).Warnf("User %s betting invalid snail", i.Member.User.Username)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s that snail doesn't exist", i.Member.User.Username), "The snail you have selected to bet is invalid, the snail isn't in the race.")
-			return
-		case models.ErrBetsClosed:
-			log.WithField("cmd", "/bet").WithError(models.ErrBetsClosed).Warnf("User %s trying to place bet that isn't open", i.Member.User.Username)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s Bets are Closed", i.Member.User.Username), "Bet's are closed so we can't accept your bet.")
-			return
-		case models.ErrNotEnough:
-			log.WithField("cmd", "/bet").WithError(models.ErrNotEnough).Warnf("User %s doesn't have the funds to place bet", i.Member.User.Username)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s Not Enough Racers", i.Member.User.Username), "We need at least 2 racers to enable bets.")
-			return
+// Every pick was given and no snail was picked twice
+func validPicks(picks []int, picked []bool) bool {
+	for index, pick := range picks {
+		if !picked[index] {
+			return false
+		}
+		for _, other := range picks[:index] {
+			if other == pick {
+				return false
+			}
 		}
-		ResponseEmbedSuccess(s, i, true, fmt.Sprintf("Bet placed for %s", snail.Name), fmt.Sprintf("You've placed a bet for %s of %d g", snail.Name, amount))
-		user.RemoveMoney(state.DB, uint64(amount))
-
 	}
+	return true
 }
 
 func (c *BetCommand) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		models.RaceActionBetType: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			// The exotic bet drop down on the race message, the user picks
+			// the bet type and then we walk them through picking the snails.
+			if len(options) != 1 {
+				log.WithField("interaction", models.RaceActionBetType).WithError(errors.New("invalid options")).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+					"There has been an issue with the action you sent, please try again.",
+				)
+				return
+			}
+
+			race, ok := state.GetRace(i.GuildID, i.ChannelID, options[0])
+			if !ok {
+				log.WithField("interaction", models.RaceActionBetType).WithError(errors.New("no existing race")).Infof("The raceid %s is not active, requested by user %s", options[0], i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", options[0]), "There is currently no race in this channel with the ID you supplied.")
+				return
+			}
+
+			betType, _ := models.ParseBetType(i.MessageComponentData().Values[0])
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseChannelMessageWithSource,
+				Data: betPickResponse(race, betType, []int{}),
+			})
+		},
+		models.RaceActionBetPick: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			// Each pick updates the message with the next pick, once all the
+			// snails have been picked it shows the bet amounts.
+			if len(options) != 3 {
+				log.WithField("interaction", models.RaceActionBetPick).WithError(errors.New("invalid options")).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+					"There has been an issue with the action you sent, please try again.",
+				)
+				return
+			}
+
+			race, ok := state.GetRace(i.GuildID, i.ChannelID, options[0])
+			if !ok {
+				log.WithField("interaction", models.RaceActionBetPick).WithError(errors.New("no existing race")).Infof("The raceid %s is not active, requested by user %s", options[0], i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", options[0]), "There is currently no race in this channel with the ID you supplied.")
+				return
+			}
+
+			betType, _ := models.ParseBetType(options[1])
+			selections, err := models.ParseSelections(options[2])
+			if err != nil {
+				log.WithField("interaction", models.RaceActionBetPick).WithError(err).Warnf("User %s sent invalid picks", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Invalid snail to bet for race %s", options[0]), "There is currently no snail with the ID you supplied.")
+				return
+			}
+
+			pick, err := models.ParseSelections(i.MessageComponentData().Values[0])
+			if err != nil || len(pick) != 1 || race.GetSnail(pick[0]) == nil {
+				log.WithField("interaction", models.RaceActionBetPick).WithError(err).Warnf("User %s picked an invalid snail", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Invalid snail to bet for race %s", options[0]), "There is currently no snail with the ID you supplied.")
+				return
+			}
+			selections = append(selections, pick[0])
+
+			data := betPickResponse(race, betType, selections)
+			if len(selections) >= betType.Picks() {
+				data = betAmountResponse(race, betType, selections)
+			}
+			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponseUpdateMessage,
+				Data: data,
+			})
+		},
+	}
 }
+
 func (c *BetCommand) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 }
+
+func (c *BetCommand) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+// Describe the picked snails, e.g. `[2] snail-a → [0] snail-b`
+func describeSelections(race *models.Race, selections []int) string {
+	names := make([]string, 0)
+	for _, selection := range selections {
+		if snail := race.GetSnail(selection); snail != nil {
+			names = append(names, fmt.Sprintf("`[%d]` %s", selection, snail.Name))
+		}
+	}
+	return strings.Join(names, " → ")
+}
+
+// The ephemeral message asking the user to pick the next snail for their bet,
+// snails that have already been picked are left out.
+func betPickResponse(race *models.Race, betType models.BetType, selections []int) *discordgo.InteractionResponseData {
+	picked := make(map[int]bool)
+	for _, selection := range selections {
+		picked[selection] = true
+	}
+
+	selectOptions := make([]discordgo.SelectMenuOption, 0)
+	for index, snail := range race.GetSnails() {
+		if picked[index] {
+			continue
+		}
+		selectOptions = append(selectOptions, discordgo.SelectMenuOption{
+			Label: fmt.Sprintf("[%d] %s", index, snail.Name),
+			Value: fmt.Sprintf("%d", index),
+		})
+	}
+
+	body := fmt.Sprintf("**%s:** %s.\n\nPick the snail you want to finish **%s**.", betType.Title(), betType.Description(), ordinal(len(selections)+1))
+	if betType.Picks() == 1 {
+		body = fmt.Sprintf("**%s:** %s.\n\nPick your snail.", betType.Title(), betType.Description())
+	}
+	if len(selections) > 0 {
+		body += fmt.Sprintf("\n\nSo far: %s", describeSelections(race, selections))
+	}
+
+	return &discordgo.InteractionResponseData{
+		Flags: discordgo.MessageFlagsEphemeral,
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       fmt.Sprintf("Looks like you want to make a %s bet", strings.ToLower(betType.Title())),
+				Color:       0x2ecc71,
+				Description: body,
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.SelectMenu{
+						MenuType: discordgo.StringSelectMenu,
+						CustomID: fmt.Sprintf("%s:%s:%s:%s", models.RaceActionBetPick, race.Id, betType, models.FormatSelections(selections)),
+						Options:  selectOptions,
+					},
+				},
+			},
+		},
+	}
+}
+
+// The ephemeral message with the predetermined bet amounts for a bet
+func betAmountResponse(race *models.Race, betType models.BetType, selections []int) *discordgo.InteractionResponseData {
+	command := fmt.Sprintf("/snailrace bet race_id: %s type: %s snail_index: %d", race.Id, betType, selections[0])
+	if len(selections) > 1 {
+		command += fmt.Sprintf(" second_index: %d", selections[1])
+	}
+	if len(selections) > 2 {
+		command += fmt.Sprintf(" third_index: %d", selections[2])
+	}
+
+	odds := ""
+	if !race.Parimutuel {
+		odds = fmt.Sprintf(" at odds of `%.02f`", race.BetOdds(betType, selections))
+	}
+
+	buttons := make([]discordgo.MessageComponent, 0)
+	for _, amount := range []int{5, 10, 20} {
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf("%dg", amount),
+			Style:    discordgo.SuccessButton,
+			CustomID: fmt.Sprintf("%s:%s:%s:%s:%d", models.RaceActionBetAmount, race.Id, betType, models.FormatSelections(selections), amount),
+		})
+	}
+
+	return &discordgo.InteractionResponseData{
+		Flags: discordgo.MessageFlagsEphemeral,
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Looks like you want to make a bet",
+				Color:       0x2ecc71,
+				Description: fmt.Sprintf("So you want to make a %s bet on %s%s. Well select one of the following predetermined amounts, or use the following command for a custom amount: \n```\n%s amount: \n```\n", strings.ToLower(betType.Title()), describeSelections(race, selections), odds, command),
+			},
+		},
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{Components: buttons},
+		},
+	}
+}
+
+// Place a bet for the user and take the money, this is shared between the
+// bet command and the bet buttons so `field` and `name` are used for logging.
+func placeBet(s *discordgo.Session, i *discordgo.InteractionCreate, field string, name string, race *models.Race, user *models.User, betType models.BetType, selections []int, amount int) {
+	err := race.PlaceBet(betType, selections, amount, user.DiscordID)
+	switch err {
+	case models.ErrInvalidAmount:
+		log.WithField(field, name).WithError(err).Infof("User %s placing a bet without an amount", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s but that isn't a bet", i.Member.User.Username), "You need to bet at least 1 g.")
+		return
+	case models.ErrInsufficientFunds:
+		log.WithField(field, name).WithError(err).Infof("User %s doesn't have the funds to place bet", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s but you can't afford the bet", i.Member.User.Username), fmt.Sprintf("You don't have enough money to place that bet, you only have %d g.", user.Money))
+		return
+	case models.ErrInvalidSnail:
+		log.WithField(field, name).WithError(models.ErrInvalidSnail).Warnf("User %s betting invalid snail", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s that snail doesn't exist", i.Member.User.Username), fmt.Sprintf("The snails you have selected to bet are invalid, a %s bet needs %d different snails from the race.", betType, betType.Picks()))
+		return
+	case models.ErrBetsClosed:
+		log.WithField(field, name).WithError(models.ErrBetsClosed).Warnf("User %s trying to place bet that isn't open", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s Bets are Closed", i.Member.User.Username), "Bet's are closed so we can't accept your bet.")
+		return
+	case models.ErrNotEnough:
+		log.WithField(field, name).WithError(models.ErrNotEnough).Warnf("User %s betting on a race without enough racers", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s Not Enough Racers", i.Member.User.Username), "We need at least 2 racers to enable bets.")
+		return
+	}
+	if err != nil {
+		log.WithField(field, name).WithError(err).Errorf("Failed to place bet for user %s", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s something went wrong", i.Member.User.Username), "We weren't able to place your bet, you haven't been charged for it.")
+		return
+	}
+	ResponseEmbedSuccess(s, i, true, fmt.Sprintf("%s bet placed", betType.Title()), fmt.Sprintf("You've placed a %s bet of %d g on %s", betType, amount, describeSelections(race, selections)))
+}
+
+func ordinal(place int) string {
+	switch place {
+	case 1:
+		return "1st"
+	case 2:
+		return "2nd"
+	case 3:
+		return "3rd"
+	}
+	return fmt.Sprintf("%dth", place)
+}
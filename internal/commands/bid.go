@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandBid bids on a snail being auctioned
+type CommandBid struct{}
+
+func (c *CommandBid) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "bid",
+		Description: "Bid on a snail being auctioned",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "auction_id",
+				Description:  "The ID of the auction to bid on",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Name:        "money",
+				Description: "How much to bid",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *CommandBid) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/bid").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		auctionId, amount := "", int64(0)
+		for _, opt := range commandOptions(i) {
+			switch opt.Name {
+			case "auction_id":
+				auctionId = opt.StringValue()
+			case "money":
+				amount = opt.IntValue()
+			}
+		}
+
+		// Auctions in another economy can't be bid on
+		auction, err := models.GetAuction(state.DB, auctionId)
+		if err == nil && !models.SameEconomy(auction.SellerID, user.DiscordID) {
+			err = models.ErrAuctionNotFound
+		}
+		if err != nil {
+			log.WithField("cmd", "/bid").WithError(err).Infof("User %s tried to bid on unknown auction %s", i.Member.User.Username, auctionId)
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("Auction %s not avaliable", auctionId), "There is no auction with the ID you supplied.")
+			return
+		}
+
+		switch err := models.PlaceAuctionBid(state.DB, auction, user.DiscordID, amount); err {
+		case nil:
+		case models.ErrOwnAuction:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that's your auction", i.Member.User.Username), "You can't bid on your own snail.")
+			return
+		case models.ErrAuctionClosed:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that auction is over", i.Member.User.Username), "The auction isn't taking bids any more.")
+			return
+		case models.ErrInvalidAmount, models.ErrBidTooLow:
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but your bid is too low", i.Member.User.Username),
+				fmt.Sprintf("You need to bid at least %dg.", auction.MinimumBid()),
+			)
+			return
+		case models.ErrInsufficientFunds:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't afford that", i.Member.User.Username), fmt.Sprintf("You only have %dg to bid with.", user.Money))
+			return
+		default:
+			log.WithField("cmd", "/bid").WithError(err).Warnf("Error bidding on auction %s for %s", auctionId, i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with placing your bid, please try again.",
+			)
+			return
+		}
+
+		log.WithField("cmd", "/bid").Infof("User %s bid %dg on auction %s", i.Member.User.Username, amount, auctionId)
+		auction.Render(s)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("You are the high bidder on %s", auction.Snail.Name),
+			fmt.Sprintf("Your bid of %dg is being held until the auction ends. If you are outbid you'll get it back straight away.", amount),
+		)
+	}
+}
+
+func (c *CommandBid) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBid) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBid) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"auction_id": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+
+			auctions, err := models.GetOpenAuctions(state.DB)
+			if err != nil {
+				log.WithField("autocomplete", "auction_id").WithError(err).Warnf("Error getting auctions for user %s", i.Member.User.Username)
+				ResponseAutocomplete(s, i, choices)
+				return
+			}
+
+			account := accountID(state, i)
+			for _, auction := range auctions {
+				if auction.SellerID == account || !models.SameEconomy(auction.SellerID, account) {
+					continue
+				}
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name:  fmt.Sprintf("%s: %s (lvl. %d), bid at least %dg", auction.AuctionID, auction.Snail.Name, auction.Snail.Level, auction.MinimumBid()),
+					Value: auction.AuctionID,
+				})
+			}
+
+			ResponseAutocomplete(s, i, choices)
+		},
+	}
+}
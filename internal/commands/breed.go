@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandBreed combines two of the user's snails into a new snail
+type CommandBreed struct{}
+
+func (c *CommandBreed) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "breed",
+		Description: fmt.Sprintf("Breed two of your snails into a new snail for %dg", models.BreedingCost),
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail_1",
+				Description:  "The first parent (sire)",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Name:         "snail_2",
+				Description:  "The second parent (dam)",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandBreed) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/breed").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		// Get both of the parents
+		parents := make(map[string]*models.Snail)
+		for _, opt := range commandOptions(i) {
+			snail, err := models.FindOwnedSnail(state.DB, *user, opt.StringValue())
+			if err != nil {
+				log.WithField("cmd", "/breed").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, opt.StringValue())
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+					"You can only breed your own snails.",
+				)
+				return
+			}
+			parents[opt.Name] = snail
+		}
+		sire, dam := parents["snail_1"], parents["snail_2"]
+
+		child, err := models.BreedSnails(state.DB, *user, sire, dam)
+		switch err {
+		case nil:
+		case models.ErrSameSnail:
+			log.WithField("cmd", "/breed").WithError(err).Infof("User %s tried to breed %s with itself", i.Member.User.Username, sire.Name)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but that's the same snail", i.Member.User.Username),
+				"You need two different snails to breed.",
+			)
+			return
+		case models.ErrTooYoung:
+			log.WithField("cmd", "/breed").WithError(err).Infof("User %s tried to breed a snail that is too young", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but your snails are too young", i.Member.User.Username),
+				fmt.Sprintf("Snails need to be at least %d days old to breed.\n\n%s\n%s", int(models.BreedingMinAge.Hours()/24), renderAge(sire), renderAge(dam)),
+			)
+			return
+		case models.ErrSnailInEscrow:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but those snails are busy", i.Member.User.Username), "One of the snails is being held for a trade or auction.")
+			return
+		case models.ErrInsufficientFunds:
+			log.WithField("cmd", "/breed").WithError(err).Infof("User %s can't afford to breed", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you can't afford to breed", i.Member.User.Username),
+				fmt.Sprintf("Breeding costs %dg for maintenance, you only have %dg.", models.BreedingCost, user.Money),
+			)
+			return
+		default:
+			log.WithField("cmd", "/breed").WithError(err).Warnf("Error breeding snails for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with breeding your snails, please try again.",
+			)
+			return
+		}
+
+		models.AwardAchievement(s, state.DB, i.ChannelID, user.DiscordID, models.AchievementEVTrainer, 1)
+		models.AwardSnailCount(s, state.DB, i.ChannelID, user.DiscordID)
+
+		// Only the traits that show are revealed, the genes stay hidden
+		traits := ""
+		if expressed := child.Genome.Traits(); len(expressed) > 0 {
+			traits = fmt.Sprintf("\n**Traits:** %s\n", strings.Join(expressed, ", "))
+		}
+
+		log.WithField("cmd", "/breed").Infof("User %s bred %s from %s and %s", i.Member.User.Username, child.Name, sire.Name, dam.Name)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("Say hello to %s!", child.Name),
+			fmt.Sprintf("**%s** and **%s** have had a baby snail, **%s**, with the following stats:\n```\n%s```%s\nThe breeding cost you %dg.", sire.Name, dam.Name, child.Name, child.Stats.RenderStatBlock(), traits, models.BreedingCost),
+		)
+	}
+}
+
+// How old a snail is, and how long until it can breed
+func renderAge(snail *models.Snail) string {
+	days := int(snail.Age().Hours() / 24)
+	if snail.CanBreed() {
+		return fmt.Sprintf("- **%s** is %d days old and ready to breed", snail.Name, days)
+	}
+
+	wait := int(math.Ceil((models.BreedingMinAge - snail.Age()).Hours() / 24))
+	return fmt.Sprintf("- **%s** is %d days old, it can breed in %d days", snail.Name, days, wait)
+}
+
+func (c *CommandBreed) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBreed) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBreed) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail_1": autocompleteOwnedSnails(state),
+		"snail_2": autocompleteOwnedSnails(state),
+	}
+}
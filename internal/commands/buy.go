@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandBuy buys a snail from the shop
+type CommandBuy struct{}
+
+func (c *CommandBuy) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "buy",
+		Description: fmt.Sprintf("Buy a snail from the shop, a random snail costs %dg", models.RandomSnailPrice),
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "listing",
+				Description:  "A snail from the shop stock, defaults to a random snail",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     false,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandBuy) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/buy").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		listingId := ""
+		for _, opt := range commandOptions(i) {
+			if opt.Name == "listing" {
+				listingId = opt.StringValue()
+			}
+		}
+
+		// Buy either a random snail or one from the stock
+		var snail *models.Snail
+		price := int64(models.RandomSnailPrice)
+		if listingId == "" {
+			snail, err = models.BuyRandomSnail(state.DB, *user)
+		} else {
+			var listing *models.ShopListing
+			if listing, err = models.GetShopListing(state.DB, listingId); err == nil {
+				price = listing.Price
+				snail, err = models.BuyShopListing(state.DB, *user, listing)
+			}
+		}
+
+		switch err {
+		case nil:
+		case models.ErrListingNotFound:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that snail isn't in stock", i.Member.User.Username), "The shop stock has changed, have a look at what is in stock now.")
+			return
+		case models.ErrListingSold:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that snail has been sold", i.Member.User.Username), "Someone beat you to it, the shop will have new stock soon.")
+			return
+		case models.ErrInsufficientFunds:
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you can't afford that", i.Member.User.Username),
+				fmt.Sprintf("The snail costs %dg, you only have %dg.", price, user.Money),
+			)
+			return
+		default:
+			log.WithField("cmd", "/buy").WithError(err).Warnf("Error buying a snail for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with buying your snail, please try again.",
+			)
+			return
+		}
+
+		log.WithField("cmd", "/buy").Infof("User %s bought %s for %dg", i.Member.User.Username, snail.Name, price)
+		models.AwardSnailCount(s, state.DB, i.ChannelID, user.DiscordID)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("Say hello to %s!", snail.Name),
+			fmt.Sprintf("You bought **%s** for %dg, it has the following stats:\n```\n%s```\nYou can race it by setting it as your racer with `/snailrace set_racer`.", snail.Name, price, snail.Stats.RenderStatBlock()),
+		)
+	}
+}
+
+func (c *CommandBuy) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBuy) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandBuy) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"listing": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+
+			listings, _, err := models.GetShopStock(state.DB)
+			if err != nil {
+				log.WithField("autocomplete", "listing").WithError(err).Warnf("Error getting the shop stock for user %s", i.Member.User.Username)
+				ResponseAutocomplete(s, i, choices)
+				return
+			}
+
+			// The stats are shown up front so the stock can be compared
+			for _, listing := range listings {
+				if listing.Sold() {
+					continue
+				}
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name: fmt.Sprintf("%s: %s for %dg (Speed %.1f, Stamina %.1f, Recovery %.1f)",
+						listing.Tier.Title(), listing.Name, listing.Price,
+						listing.Stats.Speed, listing.Stats.Stamina, listing.Stats.Recovery,
+					),
+					Value: fmt.Sprintf("%d", listing.ID),
+				})
+			}
+
+			ResponseAutocomplete(s, i, choices)
+		},
+	}
+}
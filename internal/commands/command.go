@@ -24,6 +24,10 @@ type DiscordAppCommand interface {
 
 	// The Discord Modal Handler for modal sumbits
 	ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)
+
+	// The Discord Autocomplete Handler for options being typed, keyed by the
+	// name of the option that is focused
+	AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate)
 }
 
 // RegisterCommand registers a command with Discord and adds a handler for the
@@ -43,6 +47,12 @@ func RegisterCommand(state *models.State, s *discordgo.Session, command DiscordA
 
 			if i.ApplicationCommandData().Options[0].Name == decleration.Name {
 				log.WithField("cmd", i.ApplicationCommandData().Options[0].Name).Infof("User %s sent command", i.Member.User.Username)
+
+				// Keep track of where users play for the leaderboards
+				if err := models.TrackGuild(state.DB, accountID(state, i), i.GuildID); err != nil {
+					log.WithError(err).Warnf("Failed to track guild for user %s", i.Member.User.Username)
+				}
+
 				command.AppHandler(state)(s, i)
 			}
 		case discordgo.InteractionMessageComponent:
@@ -60,6 +70,24 @@ func RegisterCommand(state *models.State, s *discordgo.Session, command DiscordA
 				}
 			}
 
+		case discordgo.InteractionApplicationCommandAutocomplete:
+			if i.ApplicationCommandData().Name != "snailrace" {
+				return
+			}
+
+			if i.ApplicationCommandData().Options[0].Name != decleration.Name {
+				return
+			}
+
+			for _, opt := range commandOptions(i) {
+				if !opt.Focused {
+					continue
+				}
+				if handler, ok := command.AutocompleteHandler(state)[opt.Name]; ok {
+					handler(s, i)
+				}
+			}
+
 		case discordgo.InteractionModalSubmit:
 			breakDown := strings.Split(i.ModalSubmitData().CustomID, ":")
 			if len(breakDown) == 0 {
@@ -80,6 +108,46 @@ func RegisterCommand(state *models.State, s *discordgo.Session, command DiscordA
 	return nil
 }
 
+// The options the user gave the subcommand, subcommands in a group have their
+// options one level further down.
+func commandOptions(i *discordgo.InteractionCreate) []*discordgo.ApplicationCommandInteractionDataOption {
+	subcommand := i.ApplicationCommandData().Options[0]
+	if subcommand.Type == discordgo.ApplicationCommandOptionSubCommandGroup && len(subcommand.Options) > 0 {
+		subcommand = subcommand.Options[0]
+	}
+	return subcommand.Options
+}
+
+// The account the member plays with in the guild, this is their discord id
+// unless the guild has its own economy.
+func accountID(state *models.State, i *discordgo.InteractionCreate) string {
+	return memberAccountID(state, i, i.Member.User.ID)
+}
+
+// The account another user plays with in the guild the interaction is from
+func memberAccountID(state *models.State, i *discordgo.InteractionCreate, discordID string) string {
+	id, err := models.AccountID(state.DB, i.GuildID, discordID)
+	if err != nil {
+		log.WithError(err).Warnf("Failed to get the economy of guild %s", i.GuildID)
+	}
+	return id
+}
+
+// Respond to an autocomplete interaction with the choices, discord only
+// allows up to 25 choices so the rest are dropped.
+func ResponseAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, choices []*discordgo.ApplicationCommandOptionChoice) {
+	if len(choices) > 25 {
+		choices = choices[:25]
+	}
+
+	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
 func ResponseEmbed(s *discordgo.Session, i *discordgo.InteractionCreate, ephemeral bool, title string, color int, msg string) {
 	flag := discordgo.MessageFlags(0)
 	if ephemeral {
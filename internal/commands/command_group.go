@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+)
+
+// CommandGroup nests related commands under a subcommand group, like
+// `/snailrace trade offer`. Discord only allows 25 subcommands on
+// /snailrace, and a group only counts as one of them.
+type CommandGroup struct {
+	Name        string
+	Description string
+	Commands    []DiscordAppCommand
+}
+
+func (c *CommandGroup) Decleration() *discordgo.ApplicationCommandOption {
+	options := make([]*discordgo.ApplicationCommandOption, 0, len(c.Commands))
+	for _, cmd := range c.Commands {
+		options = append(options, cmd.Decleration())
+	}
+
+	return &discordgo.ApplicationCommandOption{
+		Name:        c.Name,
+		Description: c.Description,
+		Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+		Options:     options,
+	}
+}
+
+// The command in the group the user picked
+func (c *CommandGroup) command(i *discordgo.InteractionCreate) DiscordAppCommand {
+	group := i.ApplicationCommandData().Options[0]
+	if len(group.Options) == 0 {
+		return nil
+	}
+
+	for _, cmd := range c.Commands {
+		if cmd.Decleration().Name == group.Options[0].Name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+func (c *CommandGroup) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		if cmd := c.command(i); cmd != nil {
+			cmd.AppHandler(state)(s, i)
+		}
+	}
+}
+
+// Buttons and modals aren't tied to a subcommand, so every command in the
+// group gets to handle them
+func (c *CommandGroup) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	handlers := map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+	for _, cmd := range c.Commands {
+		for action, handler := range cmd.ActionHandler(state, options...) {
+			handlers[action] = handler
+		}
+	}
+	return handlers
+}
+
+func (c *CommandGroup) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	handlers := map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+	for _, cmd := range c.Commands {
+		for modal, handler := range cmd.ModalHandler(state, options...) {
+			handlers[modal] = handler
+		}
+	}
+	return handlers
+}
+
+// Commands in the group can share option names, so the option is completed by
+// the command the user is typing
+func (c *CommandGroup) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	handlers := map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+	for _, cmd := range c.Commands {
+		for option := range cmd.AutocompleteHandler(state) {
+			option := option
+			handlers[option] = func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+				cmd := c.command(i)
+				if cmd == nil {
+					return
+				}
+				if handler, ok := cmd.AutocompleteHandler(state)[option]; ok {
+					handler(s, i)
+				}
+			}
+		}
+	}
+	return handlers
+}
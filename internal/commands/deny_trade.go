@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandDenyTrade denies a trade offered to the user, or withdraws one they
+// offered
+type CommandDenyTrade struct{}
+
+func (c *CommandDenyTrade) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "deny",
+		Description: "Deny a trade offered to you, or withdraw your own offer",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "trade_id",
+				Description:  "The ID of the trade to deny",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandDenyTrade) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		options := commandOptions(i)
+		if len(options) != 1 {
+			log.WithField("cmd", "/deny").Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true, "Invalid trade", "You need to supply the ID of the trade to deny.")
+			return
+		}
+
+		denyTrade(s, i, state, "cmd", "/deny", options[0].StringValue())
+	}
+}
+
+func (c *CommandDenyTrade) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandDenyTrade) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandDenyTrade) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"trade_id": autocompletePendingTrades(state, false),
+	}
+}
@@ -34,7 +34,7 @@ func (c *CommandDisplayProfile) Decleration() *discordgo.ApplicationCommandOptio
 
 func GetRequestedUser(s *discordgo.Session, i *discordgo.InteractionCreate) (*discordgo.User, bool, error) {
 	if len(i.ApplicationCommandData().Options) > 0 {
-		for _, opt := range i.ApplicationCommandData().Options[0].Options {
+		for _, opt := range commandOptions(i) {
 			switch opt.Name {
 			case "user-option":
 				usr, err := s.User(opt.Value.(string))
@@ -64,7 +64,7 @@ func (c *CommandDisplayProfile) AppHandler(state *models.State) func(s *discordg
 
 		// Check if the user is initialised, if the user isn't initialised then
 		// we need to tell them to initialise their account.
-		user, err := models.GetUserByDiscordID(state.DB, discorduser.ID)
+		user, err := models.GetUserByDiscordID(state.DB, memberAccountID(state, i, discorduser.ID))
 		if err != nil {
 			log.WithField("cmd", "/display").WithError(err).Infof("User %s is not initialised", discorduser.Username)
 			if personal {
@@ -82,7 +82,10 @@ func (c *CommandDisplayProfile) AppHandler(state *models.State) func(s *discordg
 		}
 
 		// get some information about the user
-		winRate := user.Wins * 100 / user.Races
+		winRate := uint64(0)
+		if user.Races > 0 {
+			winRate = user.Wins * 100 / user.Races
+		}
 		levelProgress := models.GetPercentageLevelProgress(state.DB, user)
 		progressBar := GenerateProgressBar(levelProgress)
 
@@ -98,10 +101,19 @@ func (c *CommandDisplayProfile) AppHandler(state *models.State) func(s *discordg
 			return
 		}
 
+		// Badges are cosmetic, so the profile still shows without them
+		badges := ""
+		progress, err := models.GetAchievementProgress(state.DB, user.DiscordID)
+		if err != nil {
+			log.WithField("cmd", "/display").WithError(err).Warnf("Could not get achievements for user %s", discorduser.Username)
+		} else if rendered := renderBadges(progress); rendered != "" {
+			badges = fmt.Sprintf("\n\n**Badges**:\n```\n%s```", rendered)
+		}
+
 		p := message.NewPrinter(language.English)
 		ResponseEmbedSuccess(s, i, personal, "Profile",
-			p.Sprintf("**Username**: %s\n\n**Level**: %d\n**Progress**: %s\n\n**Win Rate**: %d%%\n**Races**: %d\n**Total Snails**: %d\n\n🐌 %s\n💰 %dg",
-				discorduser.Username, user.Level, progressBar, winRate, user.Races, len(allSnails), activeSnail.Name, user.Money))
+			p.Sprintf("**Username**: %s\n\n**Level**: %d\n**Progress**: %s\n\n**Win Rate**: %d%%\n**Races**: %d\n**Total Snails**: %d\n\n🐌 %s (%s)\n💰 %dg%s",
+				discorduser.Username, user.Level, progressBar, winRate, user.Races, len(allSnails), activeSnail.Name, activeSnail.RenderMood(), user.Money, badges))
 	}
 }
 
@@ -119,3 +131,7 @@ func (c *CommandDisplayProfile) ActionHandler(state *models.State, options ...st
 func (c *CommandDisplayProfile) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 }
+
+func (c *CommandDisplayProfile) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandGift gives money and/or a snail to another user
+type CommandGift struct{}
+
+func (c *CommandGift) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "gift",
+		Description: "Give money and/or a snail to another user",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "user",
+				Description: "The user to give the gift to",
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Required:    true,
+			},
+			{
+				Name:        "money",
+				Description: "The money to give",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+			},
+			{
+				Name:         "snail",
+				Description:  "The snail to give",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandGift) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/gift").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		var (
+			other      *discordgo.User
+			money      int64
+			snailQuery string
+		)
+		for _, opt := range commandOptions(i) {
+			switch opt.Name {
+			case "user":
+				other = opt.UserValue(s)
+			case "money":
+				money = opt.IntValue()
+			case "snail":
+				snailQuery = opt.StringValue()
+			}
+		}
+
+		// Make sure the other user can receive the gift
+		if other == nil || other.ID == i.Member.User.ID {
+			log.WithField("cmd", "/gift").Infof("User %s tried to gift themselves", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't gift yourself", i.Member.User.Username), "Pick someone else to give your gift to.")
+			return
+		}
+		otherUser, err := models.GetUserByDiscordID(state.DB, memberAccountID(state, i, other.ID))
+		if err != nil {
+			log.WithField("cmd", "/gift").WithError(err).Infof("User %s tried to gift uninitialised user %s", i.Member.User.Username, other.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but %s isn't initialised", i.Member.User.Username, other.Username),
+				fmt.Sprintf("%s will need to initialise their account with `/snailrace init` before you can give them gifts.", other.Username),
+			)
+			return
+		}
+
+		// Get the snail being given, if there is one
+		var snail *models.Snail
+		if snailQuery != "" {
+			if snail, err = models.FindOwnedSnail(state.DB, *user, snailQuery); err != nil {
+				log.WithField("cmd", "/gift").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, snailQuery)
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+					"You can only give away your own snails.",
+				)
+				return
+			}
+		}
+
+		// Snails can't change hands mid race
+		var gift *models.Gift
+		err = state.WhileIdle(func() (err error) {
+			gift, err = models.GiveGift(state.DB, *user, *otherUser, money, snail)
+			return err
+		}, giftSnailIDs(snail)...)
+		switch err {
+		case nil:
+		case models.ErrSnailRacing:
+			log.WithField("cmd", "/gift").Infof("User %s tried to gift racing snail %s", i.Member.User.Username, snail.Name)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but %s is racing", i.Member.User.Username, snail.Name),
+				"Snails can't be given away while they are in a race, try again once the race is over.",
+			)
+			return
+		case models.ErrNothingToGift:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that gift is empty", i.Member.User.Username), "Add some money or a snail to your gift.")
+			return
+		case models.ErrInvalidAmount:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that isn't money", i.Member.User.Username), "You can't gift a negative amount of money.")
+			return
+		case models.ErrLastSnail:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you need a snail", i.Member.User.Username), "You can't give away your last snail.")
+			return
+		case models.ErrSnailInEscrow:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but %s is busy", i.Member.User.Username, snail.Name), "The snail is being held for a trade or auction.")
+			return
+		case models.ErrInsufficientFunds:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't afford that", i.Member.User.Username), fmt.Sprintf("You only have %dg to give.", user.Money))
+			return
+		case models.ErrGiftCapReached:
+			moneyLeft, snailsLeft, _ := models.GiftAllowance(state.DB, user.DiscordID)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but that's too generous", i.Member.User.Username),
+				fmt.Sprintf(
+					"Users can give and receive up to %dg and %d snails a day. You can give %dg and %d snails more today, but %s may not be able to receive that much.",
+					models.DailyGiftMoneyCap, models.DailyGiftSnailCap, moneyLeft, snailsLeft, other.Username,
+				),
+			)
+			return
+		default:
+			log.WithField("cmd", "/gift").WithError(err).Warnf("Error giving gift from %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with giving your gift, please try again.",
+			)
+			return
+		}
+
+		log.WithField("cmd", "/gift").Infof("User %s gave %s a gift", i.Member.User.Username, other.Username)
+
+		// Show everyone how generous the user is
+		body := fmt.Sprintf("<@%s> has given <@%s> a gift!\n\n", i.Member.User.ID, other.ID)
+		if gift.Money > 0 {
+			body += fmt.Sprintf("💰 **%dg**\n", gift.Money)
+		}
+		if snail != nil {
+			body += fmt.Sprintf("🐌 **%s** (lvl. %d)\n```\n%s```", snail.Name, snail.Level, snail.Stats.RenderStatBlock())
+		}
+		ResponseEmbedSuccess(s, i, false, "🎁 A Generous Gift", body)
+
+		if snail != nil {
+			models.AwardAchievement(s, state.DB, i.ChannelID, user.DiscordID, models.AchievementPureKindness, 1)
+			models.AwardSnailCount(s, state.DB, i.ChannelID, otherUser.DiscordID)
+		}
+	}
+}
+
+func (c *CommandGift) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandGift) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandGift) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
+
+// The id of the snail being given, if there is one
+func giftSnailIDs(snail *models.Snail) []uint {
+	if snail == nil {
+		return nil
+	}
+	return []uint{snail.ID}
+}
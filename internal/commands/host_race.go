@@ -19,11 +19,28 @@ func (c *CommandHostRace) Decleration() *discordgo.ApplicationCommandOption {
 		Description: "Let's host a race",
 		Type:        discordgo.ApplicationCommandOptionSubCommand,
 		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "length",
+				Description: "The length of the race track, longer races need more stamina. Defaults to 1m.",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices:     raceLengthChoices(),
+			},
+			{
+				Name:         "snail",
+				Description:  "The snail to enter, defaults to your racer",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Autocomplete: true,
+			},
 			{
 				Name:        "no-bets",
 				Description: "This flag skips the ability to place bets.",
 				Type:        discordgo.ApplicationCommandOptionBoolean,
 			},
+			{
+				Name:        "parimutuel",
+				Description: "Pool all the bets and split them between the winners instead of paying fixed odds.",
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+			},
 			{
 				Name:        "dont-fill",
 				Description: "If this is set, then there wont be any additional snails added if the race has less than 4 snails",
@@ -38,11 +55,22 @@ func (c *CommandHostRace) Decleration() *discordgo.ApplicationCommandOption {
 	}
 }
 
+func raceLengthChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, length := range models.RaceLengths {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  length.String(),
+			Value: length.String(),
+		})
+	}
+	return choices
+}
+
 func (c *CommandHostRace) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		// Check if the user is initialised, if the user isn't initialised then
 		// we need to tell them to initialise their account.
-		user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 		if err != nil {
 			log.WithField("cmd", "/host").WithError(err).Infof("No record for user %s", i.Member.User.Username)
 
@@ -53,33 +81,78 @@ func (c *CommandHostRace) AppHandler(state *models.State) func(s *discordgo.Sess
 			return
 		}
 
-		// We need to get the active snail of the host to automatically add them
-		// to the race
-		snail, err := models.GetActiveSnail(state.DB, *user)
+		// We need to get the snail the host picked, or their active snail, to
+		// automatically add them to the race
+		snailQuery := ""
+		if len(i.ApplicationCommandData().Options) > 0 {
+			for _, opt := range commandOptions(i) {
+				if opt.Name == "snail" {
+					snailQuery = opt.StringValue()
+				}
+			}
+		}
+		snail, err := getOptionSnail(state, user, snailQuery)
 		if err != nil {
-			log.WithField("cmd", "/host").WithError(err).Warnf("Error getting active snail for %s", i.Member.User.Username)
+			log.WithField("cmd", "/host").WithError(err).Warnf("Error getting snail %s for %s", snailQuery, i.Member.User.Username)
 
 			ResponseEmbedFail(s, i, true,
-				fmt.Sprintf("I'm sorry %s, but we couldn't get your active snail", i.Member.User.Username),
-				"There has been an issue with the action you sent, please try again.",
+				fmt.Sprintf("I'm sorry %s, but we couldn't get your snail", i.Member.User.Username),
+				"We couldn't find the snail you wanted to race, please try again.",
 			)
 			return
 		}
 
+		// Snails held for a trade or auction can't race
+		if snail.InEscrow() {
+			log.WithField("cmd", "/host").Infof("User %s tried to race a snail in escrow", i.Member.User.Username)
+			ResponseEmbedInfo(s, i, true, fmt.Sprintf("%s is busy %s", snail.Name, i.Member.User.Username), "That snail is being held for a trade or auction, so it can't race right now.")
+			return
+		}
+
 		// Generate the race and add the host as the first snail
-		race := state.NewRace(s, i.ChannelID, i.Member.User)
-		race.AddSnail(snail)
+		race, err := state.NewRace(s, i.GuildID, i.ChannelID, i.Member.User)
+		if err != nil {
+			log.WithField("cmd", "/host").WithError(err).Infof("User %s tried to host a race while shutting down", i.Member.User.Username)
+			ResponseEmbedInfo(s, i, true, fmt.Sprintf("Sorry %s, no new races right now", i.Member.User.Username), "The bot is about to restart, please host your race again in a few minutes.")
+			return
+		}
+
+		// The snail may have been put into escrow since we checked, the race
+		// hasn't started yet so it can just be dropped
+		switch err := race.AddSnail(snail); err {
+		case nil:
+		case models.ErrSnailInEscrow:
+			race.EndRace()
+			log.WithField("cmd", "/host").Infof("User %s tried to race a snail in escrow", i.Member.User.Username)
+			ResponseEmbedInfo(s, i, true, fmt.Sprintf("%s is busy %s", snail.Name, i.Member.User.Username), "That snail is being held for a trade or auction, so it can't race right now.")
+			return
+		default:
+			race.EndRace()
+			log.WithField("cmd", "/host").WithError(err).Warnf("Error adding %s to a new race for %s", snail.Name, i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with hosting your race, please try again.",
+			)
+			return
+		}
 
 		// Add flags to the Race
 		if len(i.ApplicationCommandData().Options) > 0 {
-			for _, opt := range i.ApplicationCommandData().Options[0].Options {
+			for _, opt := range commandOptions(i) {
 				switch opt.Name {
+				case "length":
+					length, _ := models.ParseRaceLength(opt.StringValue())
+					race.SetLength(length)
 				case "no-bets":
 					race.SetNoBets()
 				case "dont-fill":
 					race.SetDontFill()
 				case "only-one":
 					race.SetOnlyOne()
+				case "parimutuel":
+					if opt.BoolValue() {
+						race.SetParimutuel()
+					}
 				}
 			}
 		}
@@ -112,7 +185,7 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 
 			// Check if the user is initialised, if the user isn't initialised then
 			// we need to tell them to initialise their account.
-			user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+			user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 			if err != nil {
 				log.WithField("interaction", models.RaceActionJoin).WithError(err).Infof("Error getting record for user %s", i.Member.User.Username)
 				ResponseEmbedFail(s, i, true,
@@ -136,22 +209,38 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 			// Check if the race exists, if it doesn't then we need to tell the
 			// user
 			raceId := options[0]
-			race, ok := state.Races[raceId]
+			race, ok := state.GetRace(i.GuildID, i.ChannelID, raceId)
 			if !ok {
 				log.WithField("interaction", models.RaceActionJoin).WithError(errors.New("no existing race")).Infof("The raceid %s is not active, requested by user %s", raceId, i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race with the ID you supplied.")
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race in this channel with the ID you supplied.")
 				return
 			}
 
-			err = race.AddSnail(snail)
-			if err != nil {
-				log.WithField("interaction", models.RaceActionJoin).WithError(err).Infof("The user %s is already in the race", i.Member.User.Username)
-				ResponseEmbedInfo(s, i, true, fmt.Sprintf("You're already in the race %s", i.Member.User.Username), "You can't join the race twice, good luck with the race!")
+			switch race.AddSnail(snail) {
+			case models.ErrSnailInEscrow:
+				log.WithField("interaction", models.RaceActionJoin).Infof("User %s tried to race a snail in escrow", i.Member.User.Username)
+				ResponseEmbedInfo(s, i, true, fmt.Sprintf("%s is busy %s", snail.Name, i.Member.User.Username), "Your racer is being held for a trade or auction, so it can't race right now.")
+				return
+			case models.ErrAlreadyJoined:
+				log.WithField("interaction", models.RaceActionJoin).Infof("The user %s is already in the race", i.Member.User.Username)
+				ResponseEmbedInfo(s, i, true, fmt.Sprintf("You're already in the race %s", i.Member.User.Username), "You can't join the race twice, good luck with the race! Use `/snailrace join` to enter a different snail.")
+				return
+			case models.ErrRaceClosed:
+				log.WithField("interaction", models.RaceActionJoin).Info("Race is closed, can't join race")
+				ResponseEmbedInfo(s, i, true, fmt.Sprintf("That race is closed %s", i.Member.User.Username), "The race you have just tried to join is currently closed.")
+				return
+			case models.ErrRaceFull:
+				log.WithField("interaction", models.RaceActionJoin).Info("Race is full, can't join race")
+				ResponseEmbedInfo(s, i, true, fmt.Sprintf("That race is full %s", i.Member.User.Username), "The race you have just tried to join is currently full. MAX 10 Snails.")
+				return
+			case models.ErrUserCap:
+				log.WithField("interaction", models.RaceActionJoin).Infof("User %s has too many snails in the race", i.Member.User.Username)
+				ResponseEmbedInfo(s, i, true, fmt.Sprintf("You've got enough snails in that race %s", i.Member.User.Username), fmt.Sprintf("You can only enter up to %d of your snails in a single race.", models.MaxUserEntrants))
 				return
 			}
 
-			// Respond to the interaction with a message
-			race.Render(s)
+			// Respond to the interaction with a message, the race re-renders
+			// itself with the new entrant
 			ResponseEmbedSuccess(s, i, true, fmt.Sprintf("You've joined the race #%s", raceId), "We've just got your snail lined up at the starting line, good luck!")
 		},
 		models.RaceActionBet: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
@@ -166,7 +255,7 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 
 			// Check if the user is initialised, if the user isn't initialised then
 			// we need to tell them to initialise their account.
-			_, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+			_, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 			if err != nil {
 				log.WithField("interaction", models.RaceActionBet).WithError(err).Infof("No record for user %s", i.Member.User.Username)
 
@@ -180,10 +269,10 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 			// Check if the race exists, if it doesn't then we need to tell the
 			// user
 			raceId := options[0]
-			race, ok := state.Races[raceId]
+			race, ok := state.GetRace(i.GuildID, i.ChannelID, raceId)
 			if !ok {
 				log.WithField("interaction", models.RaceActionJoin).WithError(errors.New("no existing race")).Infof("The raceid %s is not active, requested by user %s", raceId, i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race with the ID you supplied.")
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race in this channel with the ID you supplied.")
 				return
 			}
 
@@ -200,41 +289,11 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 
 			s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Flags: discordgo.MessageFlagsEphemeral,
-					Embeds: []*discordgo.MessageEmbed{
-						{
-							Title:       "Looks like you want to make a bet",
-							Color:       0x2ecc71,
-							Description: fmt.Sprintf("So you want to make a bet on %s. Well select one of the following predetermined amounts, or use the following command for a custom amount: \n```\n/snailrace bet race_id: %s snail_index: %d amount: \n```\n", snail.Name, raceId, snailIndex),
-						},
-					},
-					Components: []discordgo.MessageComponent{
-						discordgo.ActionsRow{
-							Components: []discordgo.MessageComponent{
-								discordgo.Button{
-									Label:    "5g",
-									Style:    discordgo.SuccessButton,
-									CustomID: fmt.Sprintf("%s:%s:%d:%d", models.RaceActionBetAmount, raceId, snailIndex, 5),
-								},
-								discordgo.Button{
-									Label:    "10g",
-									Style:    discordgo.SuccessButton,
-									CustomID: fmt.Sprintf("%s:%s:%d:%d", models.RaceActionBetAmount, raceId, snailIndex, 10),
-								},
-								discordgo.Button{
-									Label:    "20g",
-									Style:    discordgo.SuccessButton,
-									CustomID: fmt.Sprintf("%s:%s:%d:%d", models.RaceActionBetAmount, raceId, snailIndex, 20),
-								},
-							},
-						},
-					},
-				},
+				Data: betAmountResponse(race, models.BetWin, []int{snailIndex}),
 			})
 		},
 		models.RaceActionBetAmount: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-			if len(options) != 3 {
+			if len(options) != 4 {
 				log.WithField("interaction", models.RaceActionBetAmount).WithError(errors.New("invalid options")).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
 				ResponseEmbedFail(s, i, true,
 					fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
@@ -245,7 +304,7 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 
 			// Check if the user is initialised, if the user isn't initialised then
 			// we need to tell them to initialise their account.
-			user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+			user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 			if err != nil {
 				log.WithField("interaction", models.RaceActionBetAmount).WithError(err).Infof("No record for user %s", i.Member.User.Username)
 				ResponseEmbedFail(s, i, true,
@@ -258,49 +317,23 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 			// Check if the race exists, if it doesn't then we need to tell the
 			// user
 			raceId := options[0]
-			race, ok := state.Races[raceId]
+			race, ok := state.GetRace(i.GuildID, i.ChannelID, raceId)
 			if !ok {
 				log.WithField("interaction", models.RaceActionBetAmount).WithError(errors.New("no existing race")).Warnf("The raceid %s is not active, requested by user %s", raceId, i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race with the ID you supplied.")
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race in this channel with the ID you supplied.")
 				return
 			}
 
-			// Check if the snail exists, if it doesn't then we need to tell the
-			// user
-			snailIndex, _ := strconv.Atoi(options[1])
-			snail := race.GetSnail(snailIndex)
-			if snail == nil {
-				log.WithField("interaction", models.RaceActionBetAmount).WithError(err).Warnf("User %s betting invalid snail", i.Member.User.Username)
+			betType, _ := models.ParseBetType(options[1])
+			selections, err := models.ParseSelections(options[2])
+			if err != nil {
+				log.WithField("interaction", models.RaceActionBetAmount).WithError(err).Warnf("User %s betting invalid snails", i.Member.User.Username)
 				ResponseEmbedFail(s, i, true, fmt.Sprintf("Invalid snail to bet for race %s", raceId), "There is currently no snail with the ID you supplied.")
 				return
 			}
 
-			// Check if the user has enough money to make the bet
-			amount, _ := strconv.Atoi(options[2])
-			if int(user.Money) < amount {
-				log.WithField("interaction", models.RaceActionBetAmount).WithError(err).Infof("User %s doesn't have the funds to place a bet", i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s but you can't afford the bet", i.Member.User.Username), fmt.Sprintf("You don't have enough money to place that bet, you only have %d g.", user.Money))
-				return
-			}
-
-			// Place the bet and remove the money from the user
-			switch race.PlaceBet(snailIndex, amount, user.DiscordID) {
-			case models.ErrInvalidSnail:
-				log.WithField("interaction", models.RaceActionBetAmount).WithError(models.ErrInvalidSnail).Warnf("User %s failed to place bet on snail", i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s that snail doesn't exist", i.Member.User.Username), "The snail you have selected to bet is invalid, the snail isn't in the race.")
-				return
-			case models.ErrBetsClosed:
-				log.WithField("interaction", models.RaceActionBetAmount).WithError(models.ErrBetsClosed).Warnf("User %s failed to place bet on snail as bets are closed", i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s Bets are Closed", i.Member.User.Username), "Bet's are closed so we can't accept your bet.")
-				return
-			case models.ErrNotEnough:
-				log.WithField("interaction", models.RaceActionBetAmount).WithError(models.ErrNotEnough).Warnf("User %s failed to place bet on snail as there aren't enough racers in the race", i.Member.User.Username)
-				ResponseEmbedFail(s, i, true, fmt.Sprintf("Sorry %s Not Enough Racers", i.Member.User.Username), "We need at least 2 racers to enable bets.")
-				return
-			}
-
-			ResponseEmbedSuccess(s, i, true, fmt.Sprintf("Bet placed for %s", snail.Name), fmt.Sprintf("You've placed a bet for %s of %d g", snail.Name, amount))
-			user.RemoveMoney(state.DB, uint64(amount))
+			amount, _ := strconv.Atoi(options[3])
+			placeBet(s, i, "interaction", models.RaceActionBetAmount, race, user, betType, selections, amount)
 		},
 	}
 }
@@ -308,3 +341,9 @@ func (c *CommandHostRace) ActionHandler(state *models.State, options ...string)
 func (c *CommandHostRace) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 }
+
+func (c *CommandHostRace) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
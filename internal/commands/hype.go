@@ -0,0 +1,250 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandHype cheers on a snail in an upcoming race, or rallies a crowd behind
+// one of the user's own snails
+type CommandHype struct{}
+
+func (c *CommandHype) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "hype",
+		Description: "Cheer on someone's snail in a race, or get a crowd behind your own",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail",
+				Description:  "The snail in a race to cheer on, or your snail for a crowd",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Name:        "mode",
+				Description: "Cheer on a snail in a race in this channel, or gather a crowd, defaults to race",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: fmt.Sprintf("Race: if the snail wins its race it is focused for %d races", models.MoodLockRaces), Value: string(models.HypeModeRace)},
+					{Name: fmt.Sprintf("Crowd: %d people can focus your snail for %d races", models.HypeCrowdSize, models.MoodLockRaces), Value: string(models.HypeModeCrowd)},
+				},
+			},
+		},
+	}
+}
+
+func (c *CommandHype) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/hype").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		snailQuery := optionString(i, "snail")
+		if models.HypeMode(optionString(i, "mode")) == models.HypeModeCrowd {
+			c.crowd(s, i, state, user, snailQuery)
+			return
+		}
+		c.race(s, i, state, user, snailQuery)
+	}
+}
+
+// Cheer on another user's snail in a race in the channel that is still taking
+// entries
+func (c *CommandHype) race(s *discordgo.Session, i *discordgo.InteractionCreate, state *models.State, user *models.User, snailQuery string) {
+	race, snail := findRaceSnail(state, i, snailQuery)
+	if snail == nil {
+		log.WithField("cmd", "/hype").Infof("User %s tried to hype snail %s that isn't in a race", i.Member.User.Username, snailQuery)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+			"You can only hype snails in a race in this channel that is still taking entries.",
+		)
+		return
+	}
+
+	switch err := models.HypeRaceSnail(state.DB, race.Id, *snail, user.DiscordID); err {
+	case nil:
+	case models.ErrOwnHype:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't hype yourself", i.Member.User.Username), "Cheer on someone else's snail, or get a crowd behind yours with the `crowd` mode.")
+		return
+	case models.ErrAlreadyHyped:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you've already hyped %s", i.Member.User.Username, snail.Name), "You can only hype each snail once a race.")
+		return
+	case models.ErrHypeClosed:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that race is over", i.Member.User.Username), "You can only hype snails in a race that is still taking entries.")
+		return
+	case models.ErrMoodLocked:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but %s can't be hyped", i.Member.User.Username, snail.Name), "Their mood is already set for the next few races.")
+		return
+	default:
+		log.WithField("cmd", "/hype").WithError(err).Warnf("Error hyping snail %s for %s", snail.Name, i.Member.User.Username)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+			"There has been an issue with hyping the snail, please try again.",
+		)
+		return
+	}
+
+	log.WithField("cmd", "/hype").Infof("User %s hyped %s in race %s", i.Member.User.Username, snail.Name, race.Id)
+	ResponseEmbedSuccess(s, i, false, "📣 Hype!",
+		fmt.Sprintf("<@%s> is cheering on 🐌 **%s** in race `%s`! If %s's snail wins it will be %s for its next %d races.", i.Member.User.ID, snail.Name, race.Id, models.Mention(snail.OwnerID), models.MoodFocused.Title(), models.MoodLockRaces),
+	)
+	models.AwardAchievement(s, state.DB, i.ChannelID, user.DiscordID, models.AchievementHypeTrain, 1)
+}
+
+// Post a crowd hype for one of the user's own snails
+func (c *CommandHype) crowd(s *discordgo.Session, i *discordgo.InteractionCreate, state *models.State, user *models.User, snailQuery string) {
+	snail, err := models.FindOwnedSnail(state.DB, *user, snailQuery)
+	if err != nil {
+		log.WithField("cmd", "/hype").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, snailQuery)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+			"You can only gather a crowd behind your own snails.",
+		)
+		return
+	}
+
+	hype, err := models.CreateCrowdHype(state.DB, *user, *snail)
+	switch err {
+	case nil:
+	case models.ErrHypeOpen:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but %s already has a crowd", i.Member.User.Username, snail.Name), "Wait for the crowd that is already gathering to finish.")
+		return
+	default:
+		log.WithField("cmd", "/hype").WithError(err).Warnf("Error creating crowd hype for %s", i.Member.User.Username)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+			"There has been an issue with gathering a crowd, please try again.",
+		)
+		return
+	}
+
+	if err := models.PostHype(s, state.DB, hype, i.ChannelID); err != nil {
+		log.WithField("cmd", "/hype").WithError(err).Warnf("Failed to post hype %s", hype.HypeID)
+	}
+
+	log.WithField("cmd", "/hype").Infof("User %s is gathering a crowd behind %s with hype %s", i.Member.User.Username, snail.Name, hype.HypeID)
+	ResponseEmbedSuccess(s, i, true,
+		fmt.Sprintf("Hype `%s` started", hype.HypeID),
+		fmt.Sprintf("If %d other people hype **%s** in the next %d minutes, they will be %s for their next %d races.", models.HypeCrowdSize, snail.Name, int(models.HypeWindow.Minutes()), models.MoodFocused.Title(), models.MoodLockRaces),
+	)
+}
+
+// Find another user's snail by id in the races in the channel that are still
+// taking entries
+func findRaceSnail(state *models.State, i *discordgo.InteractionCreate, snailQuery string) (*models.Race, *models.Snail) {
+	id, err := strconv.ParseUint(snailQuery, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+
+	for _, race := range state.OpenRaces(i.GuildID, i.ChannelID) {
+		for _, snail := range race.GetSnails() {
+			if snail.ID != 0 && uint64(snail.ID) == id {
+				return race, snail
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (c *CommandHype) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		models.HypeActionCheer: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if len(options) != 1 {
+				log.WithField("interaction", models.HypeActionCheer).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, "Invalid hype", "We couldn't work out which hype that was, please try again.")
+				return
+			}
+
+			user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+			if err != nil {
+				log.WithField("interaction", models.HypeActionCheer).WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+					"You'll need to initialise your account with `/snailrace init` to use this command.",
+				)
+				return
+			}
+
+			hype, err := models.GetHype(state.DB, options[0])
+			if err == nil {
+				err = models.CheerCrowdHype(state.DB, hype, user.DiscordID)
+			}
+			switch err {
+			case nil:
+			case models.ErrHypeNotFound:
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("Hype %s not avaliable", options[0]), "There is no hype with the ID you supplied.")
+				return
+			case models.ErrHypeClosed:
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that hype is over", i.Member.User.Username), "The crowd has already gone home.")
+				return
+			case models.ErrOwnHype:
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't hype yourself", i.Member.User.Username), "You'll need other people to get behind your snail.")
+				return
+			case models.ErrAlreadyHyped:
+				ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you're already in the crowd", i.Member.User.Username), "You can only hype each crowd once.")
+				return
+			default:
+				log.WithField("interaction", models.HypeActionCheer).WithError(err).Warnf("Error cheering hype %s for %s", options[0], i.Member.User.Username)
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+					"There has been an issue with hyping the snail, please try again.",
+				)
+				return
+			}
+
+			log.WithField("interaction", models.HypeActionCheer).Infof("User %s cheered hype %s", i.Member.User.Username, hype.HypeID)
+			hype.Render(s)
+			ResponseEmbedSuccess(s, i, true, "📣 Hype!", fmt.Sprintf("You're cheering on **%s**, %d/%d people so far.", hype.Snail.Name, hype.Cheers, models.HypeCrowdSize))
+			models.AwardAchievement(s, state.DB, i.ChannelID, user.DiscordID, models.AchievementHypeTrain, 1)
+		},
+	}
+}
+
+func (c *CommandHype) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandHype) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	owned := autocompleteOwnedSnails(state)
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if models.HypeMode(optionString(i, "mode")) == models.HypeModeCrowd {
+				owned(s, i)
+				return
+			}
+
+			// Suggest everyone else's snails in the races in the channel
+			account := accountID(state, i)
+			typed := strings.ToLower(focusedOptionValue(i))
+			choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+			for _, race := range state.OpenRaces(i.GuildID, i.ChannelID) {
+				for _, snail := range race.GetSnails() {
+					if snail.ID == 0 || snail.OwnerID == account || !strings.Contains(strings.ToLower(snail.Name), typed) {
+						continue
+					}
+					choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+						Name:  fmt.Sprintf("%s (lvl. %d) in race %s", snail.Name, snail.Level, race.Id),
+						Value: fmt.Sprintf("%d", snail.ID),
+					})
+				}
+			}
+			ResponseAutocomplete(s, i, choices)
+		},
+	}
+}
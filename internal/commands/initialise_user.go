@@ -26,7 +26,7 @@ func (c *CommandInitialise) AppHandler(state *models.State) func(s *discordgo.Se
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 
 		// Check if the user already has an account
-		user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 		if err != nil && err != gorm.ErrRecordNotFound {
 			log.WithField("cmd", "/init").WithError(err).Warnf("Error getting user %s", i.Member.User.Username)
 			c.respondWithFail(s, i)
@@ -37,7 +37,7 @@ func (c *CommandInitialise) AppHandler(state *models.State) func(s *discordgo.Se
 		// create it and then create a snail for them.
 		if err == gorm.ErrRecordNotFound {
 			log.WithField("cmd", "/init").Infof("Creating record for user %s", i.Member.User.Username)
-			c.respondCreateNew(s, i, state.DB)
+			c.respondCreateNew(s, i, state.DB, accountID(state, i))
 			return
 		}
 
@@ -55,9 +55,13 @@ func (c *CommandInitialise) ModalHandler(state *models.State, options ...string)
 	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 }
 
-func (c CommandInitialise) respondCreateNew(s *discordgo.Session, i *discordgo.InteractionCreate, db *gorm.DB) {
+func (c *CommandInitialise) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c CommandInitialise) respondCreateNew(s *discordgo.Session, i *discordgo.InteractionCreate, db *gorm.DB, discordID string) {
 	// Create a new user
-	user, err := models.CreateUser(db, i.Member.User.ID)
+	user, err := models.CreateUser(db, discordID)
 	if err != nil {
 		log.WithField("cmd", "/init").WithError(err).Warnf("Error creating user %s", i.Member.User.Username)
 		c.respondWithFail(s, i)
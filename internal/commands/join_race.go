@@ -22,21 +22,30 @@ func (c *CommandJoinRace) Decleration() *discordgo.ApplicationCommandOption {
 				Type:        discordgo.ApplicationCommandOptionString,
 				Required:    true,
 			},
+			{
+				Name:         "snail",
+				Description:  "The snail to enter, defaults to your racer",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Autocomplete: true,
+			},
 		},
 	}
 }
 
 func (c *CommandJoinRace) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		raceId := ""
+		raceId, snailQuery := "", ""
 
 		// The Join Action acts as the command /snailrace join <race_id>
 		// If the caller doesn't supply the `race_id` then we need to
 		// through and error, theoretically this should nevery error
 		if len(i.ApplicationCommandData().Options) > 0 {
-			for _, opt := range i.ApplicationCommandData().Options[0].Options {
-				if opt.Name == "race_id" {
+			for _, opt := range commandOptions(i) {
+				switch opt.Name {
+				case "race_id":
 					raceId = opt.Value.(string)
+				case "snail":
+					snailQuery = opt.StringValue()
 				}
 			}
 		}
@@ -53,7 +62,7 @@ func (c *CommandJoinRace) AppHandler(state *models.State) func(s *discordgo.Sess
 
 		// Check if the user is initialised, if the user isn't initialised then
 		// we need to tell them to initialise their account.
-		user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 		if err != nil {
 			log.WithField("cmd", "/join").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
 			ResponseEmbedFail(s, i, true,
@@ -63,28 +72,33 @@ func (c *CommandJoinRace) AppHandler(state *models.State) func(s *discordgo.Sess
 			return
 		}
 
-		// We neet to get the user's active snail to add to the race
-		snail, err := models.GetActiveSnail(state.DB, *user)
+		// We neet to get the snail the user picked, or their active snail, to
+		// add to the race
+		snail, err := getOptionSnail(state, user, snailQuery)
 		if err != nil {
-			log.WithField("cmd", "/join").WithError(err).Infof("User %s has no active snail", i.Member.User.Username)
+			log.WithField("cmd", "/join").WithError(err).Infof("User %s has no snail %s", i.Member.User.Username, snailQuery)
 			ResponseEmbedFail(s, i, true,
-				fmt.Sprintf("I'm sorry %s, but we couldn't get your active snail", i.Member.User.Username),
-				"There has been an issue with the action you sent, please try again.",
+				fmt.Sprintf("I'm sorry %s, but we couldn't get your snail", i.Member.User.Username),
+				"We couldn't find the snail you wanted to race, please try again.",
 			)
 			return
 		}
 
 		// Fetch the race from the supplied raceId, if there is no race with the
 		// RaceId then warn the user.
-		race, ok := state.Races[raceId]
+		race, ok := state.GetRace(i.GuildID, i.ChannelID, raceId)
 		if !ok {
 			log.WithField("cmd", "/join").Infof("No race with the supplied raceId: %s", raceId)
-			ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race with the ID you supplied.")
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is currently no race in this channel with the ID you supplied.")
 			return
 		}
 
 		// Add the snail to the race and
 		switch race.AddSnail(snail) {
+		case models.ErrSnailInEscrow:
+			log.WithField("cmd", "/join").Infof("User %s tried to race a snail in escrow", i.Member.User.Username)
+			ResponseEmbedInfo(s, i, true, fmt.Sprintf("%s is busy %s", snail.Name, i.Member.User.Username), "That snail is being held for a trade or auction, so it can't race right now.")
+			return
 		case models.ErrAlreadyJoined:
 			log.WithField("cmd", "/join").Infof("User %s already in race", i.Member.User.Username)
 			ResponseEmbedInfo(s, i, true, fmt.Sprintf("You're already in the race %s", i.Member.User.Username), "You can't join the race twice, good luck with the race!")
@@ -97,11 +111,14 @@ func (c *CommandJoinRace) AppHandler(state *models.State) func(s *discordgo.Sess
 			log.WithField("cmd", "/join").Info("Race is full, can't join race")
 			ResponseEmbedInfo(s, i, true, fmt.Sprintf("That race is full %s", i.Member.User.Username), "The race you have just tried to join is currently full. MAX 10 Snails.")
 			return
+		case models.ErrUserCap:
+			log.WithField("cmd", "/join").Infof("User %s has too many snails in the race", i.Member.User.Username)
+			ResponseEmbedInfo(s, i, true, fmt.Sprintf("You've got enough snails in that race %s", i.Member.User.Username), fmt.Sprintf("You can only enter up to %d of your snails in a single race.", models.MaxUserEntrants))
+			return
 
 		}
 
-		race.Render(s)
-		ResponseEmbedSuccess(s, i, true, fmt.Sprintf("You've joined the race #%s", raceId), "We've just got your snail lined up at the starting line, good luck!")
+		ResponseEmbedSuccess(s, i, true, fmt.Sprintf("You've joined the race #%s", raceId), fmt.Sprintf("We've just got %s lined up at the starting line, good luck!", snail.Name))
 	}
 }
 
@@ -112,3 +129,9 @@ func (c *CommandJoinRace) ActionHandler(state *models.State, options ...string)
 func (c *CommandJoinRace) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 }
+
+func (c *CommandJoinRace) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
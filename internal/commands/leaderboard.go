@@ -0,0 +1,113 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandLeaderboard shows the top users or snails
+type CommandLeaderboard struct{}
+
+func (c *CommandLeaderboard) Decleration() *discordgo.ApplicationCommandOption {
+	boards := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+	for _, kind := range models.LeaderboardKinds {
+		boards = append(boards, &discordgo.ApplicationCommandOptionChoice{Name: kind.Title(), Value: string(kind)})
+	}
+
+	return &discordgo.ApplicationCommandOption{
+		Name:        "leaderboard",
+		Description: "Show the top players or snails",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "board",
+				Description: "What to rank by, defaults to level",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    false,
+				Choices:     boards,
+			},
+			{
+				Name:        "global",
+				Description: "Rank everyone instead of just this server",
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Required:    false,
+			},
+		},
+	}
+}
+
+func (c *CommandLeaderboard) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		kind, guildID := models.LeaderboardLevel, i.GuildID
+		for _, opt := range commandOptions(i) {
+			switch opt.Name {
+			case "board":
+				kind = models.LeaderboardKind(opt.StringValue())
+			case "global":
+				if opt.BoolValue() {
+					guildID = ""
+				}
+			}
+		}
+
+		entries, err := models.GetLeaderboard(state.DB, kind, guildID)
+		if err != nil {
+			log.WithField("cmd", "/leaderboard").WithError(err).Warnf("Error getting the %s leaderboard", kind)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with getting the leaderboard, please try again.",
+			)
+			return
+		}
+
+		body := ""
+		for _, entry := range entries {
+			body += fmt.Sprintf("**#%d** %s\n", entry.Rank, renderLeaderboardEntry(kind, entry))
+		}
+		if body == "" {
+			body = "Nobody is on the leaderboard yet, go race!\n"
+		}
+
+		// The caller's own place, even if they aren't in the top
+		if entry, err := models.GetLeaderboardRank(state.DB, kind, guildID, accountID(state, i)); err == nil {
+			body += fmt.Sprintf("\n**Your rank**: #%d %s", entry.Rank, renderLeaderboardEntry(kind, *entry))
+		}
+
+		scope := "This Server"
+		if guildID == "" {
+			scope = "Global"
+		}
+		ResponseEmbedInfo(s, i, false, fmt.Sprintf("%s Leaderboard (%s)", kind.Title(), scope), body)
+	}
+}
+
+func renderLeaderboardEntry(kind models.LeaderboardKind, entry models.LeaderboardEntry) string {
+	switch kind {
+	case models.LeaderboardWins:
+		return fmt.Sprintf("%s with %d wins from %d races", models.Mention(entry.DiscordID), entry.Wins, entry.Races)
+	case models.LeaderboardXP:
+		return fmt.Sprintf("%s with %d XP", models.Mention(entry.DiscordID), entry.Score)
+	case models.LeaderboardSnails:
+		winRate := uint64(0)
+		if entry.Races > 0 {
+			winRate = entry.Wins * 100 / entry.Races
+		}
+		return fmt.Sprintf("🐌 %s (%s) with %d wins from %d races (%d%%)", entry.SnailName, models.Mention(entry.DiscordID), entry.Wins, entry.Races, winRate)
+	}
+	return fmt.Sprintf("%s at level %d", models.Mention(entry.DiscordID), entry.Level)
+}
+
+func (c *CommandLeaderboard) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandLeaderboard) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandLeaderboard) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
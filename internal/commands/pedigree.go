@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandPedigree shows a snail's family tree
+type CommandPedigree struct{}
+
+func (c *CommandPedigree) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "pedigree",
+		Description: "Show the family tree of one of your snails",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail",
+				Description:  "The snail to show, defaults to your racer",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     false,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandPedigree) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/pedigree").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		query := ""
+		for _, opt := range commandOptions(i) {
+			if opt.Name == "snail" {
+				query = opt.StringValue()
+			}
+		}
+
+		snail, err := getOptionSnail(state, user, query)
+		if err != nil {
+			log.WithField("cmd", "/pedigree").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, query)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+				"You can only show the pedigree of your own snails.",
+			)
+			return
+		}
+
+		pedigree := models.GetPedigree(state.DB, snail, models.PedigreeGenerations)
+
+		body := "Stats are shown as `speed/stamina/recovery/weight`.\n```\n"
+		body += renderPedigreeSnail(pedigree.Snail) + "\n"
+		body += renderPedigreeParents(pedigree, "")
+		body += "```"
+		if !snail.IsBred() {
+			body += fmt.Sprintf("\n%s wasn't bred, so its family is a mystery.", snail.Name)
+		}
+
+		ResponseEmbedInfo(s, i, false, fmt.Sprintf("The family of %s", snail.Name), body)
+	}
+}
+
+// Render the parents of the pedigree as branches of the tree, the prefix is
+// the lines of the branches above
+func renderPedigreeParents(pedigree *models.Pedigree, prefix string) string {
+	parents := make([]string, 0)
+	branches := make([]*models.Pedigree, 0)
+	if pedigree.Sire != nil {
+		parents, branches = append(parents, "Sire"), append(branches, pedigree.Sire)
+	}
+	if pedigree.Dam != nil {
+		parents, branches = append(parents, "Dam"), append(branches, pedigree.Dam)
+	}
+
+	tree := ""
+	for index, branch := range branches {
+		joint, indent := "├─", "│  "
+		if index == len(branches)-1 {
+			joint, indent = "└─", "   "
+		}
+
+		tree += fmt.Sprintf("%s%s %s: %s\n", prefix, joint, parents[index], renderPedigreeSnail(branch.Snail))
+		tree += renderPedigreeParents(branch, prefix+indent)
+	}
+	return tree
+}
+
+func renderPedigreeSnail(snail *models.Snail) string {
+	line := fmt.Sprintf("%s (lvl. %d) %.1f/%.1f/%.1f/%.1f", snail.Name, snail.Level, snail.Stats.Speed, snail.Stats.Stamina, snail.Stats.Recovery, snail.Stats.Weight)
+	if traits := snail.Genome.Traits(); len(traits) > 0 {
+		line += " " + strings.Join(traits, ", ")
+	}
+	return line
+}
+
+func (c *CommandPedigree) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandPedigree) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandPedigree) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
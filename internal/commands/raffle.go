@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandRaffle buys tickets in the raffle for a prize snail
+type CommandRaffle struct{}
+
+func (c *CommandRaffle) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "raffle",
+		Description: fmt.Sprintf("Buy tickets in the raffle for a prize snail, %dg each", models.RaffleTicketPrice),
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "num",
+				Description: "How many tickets to buy, defaults to 1",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Required:    false,
+			},
+		},
+	}
+}
+
+func (c *CommandRaffle) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/raffle").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		num := int64(1)
+		for _, opt := range commandOptions(i) {
+			if opt.Name == "num" {
+				num = opt.IntValue()
+			}
+		}
+
+		raffle, err := models.BuyRaffleTickets(state.DB, user.DiscordID, num)
+		switch err {
+		case nil:
+		case models.ErrInvalidAmount:
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you can't buy that many tickets", i.Member.User.Username),
+				fmt.Sprintf("You can buy between 1 and %d tickets at a time.", models.MaxRaffleTickets),
+			)
+			return
+		case models.ErrInsufficientFunds:
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you can't afford that", i.Member.User.Username),
+				fmt.Sprintf("%d tickets cost %dg, you only have %dg.", num, num*models.RaffleTicketPrice, user.Money),
+			)
+			return
+		default:
+			log.WithField("cmd", "/raffle").WithError(err).Warnf("Error buying raffle tickets for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with buying your tickets, please try again.",
+			)
+			return
+		}
+
+		total, owned, err := models.CountRaffleTickets(state.DB, raffle.ID, user.DiscordID)
+		if err != nil {
+			log.WithField("cmd", "/raffle").WithError(err).Warnf("Error counting raffle tickets for %s", i.Member.User.Username)
+		}
+
+		log.WithField("cmd", "/raffle").Infof("User %s bought %d tickets in raffle %d", i.Member.User.Username, num, raffle.ID)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("You bought %d raffle tickets", num),
+			fmt.Sprintf("You have %d of the %d tickets in raffle #%d, which is drawn <t:%d:R>. The winner gets a brand new expert snail!", owned, total, raffle.ID, raffle.DrawAt.Unix()),
+		)
+	}
+}
+
+func (c *CommandRaffle) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandRaffle) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandRaffle) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
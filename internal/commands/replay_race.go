@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandReplayRace re-renders a finished race from the race history
+type CommandReplayRace struct{}
+
+func (c *CommandReplayRace) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "replay",
+		Description: "Watch a finished race again",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "race_id",
+				Description: "The race to replay",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (c *CommandReplayRace) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		raceId := ""
+		if len(i.ApplicationCommandData().Options) > 0 {
+			for _, opt := range commandOptions(i) {
+				if opt.Name == "race_id" {
+					raceId = opt.StringValue()
+				}
+			}
+		}
+
+		// Fetch the record of the race, only finished races are recorded so
+		// a running race will also not be found.
+		record, err := models.GetRaceRecord(state.DB, raceId)
+		if err != nil {
+			log.WithField("cmd", "/replay").WithError(err).Infof("No race record for raceId: %s", raceId)
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("Race %s not avaliable", raceId), "There is no finished race with the ID you supplied.")
+			return
+		}
+
+		// Replay the race as a seperate process
+		go models.ReplayRace(s, record, i.ChannelID)
+
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("Replaying race #%s", raceId),
+			"Grab some popcorn, the snails are lining up again.",
+		)
+	}
+}
+
+func (c *CommandReplayRace) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandReplayRace) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandReplayRace) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
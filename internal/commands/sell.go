@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandSell sells one of the user's snails to the shop
+type CommandSell struct{}
+
+func (c *CommandSell) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "sell",
+		Description: fmt.Sprintf("Sell one of your snails to the shop for %dg", models.SnailSellPrice),
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail",
+				Description:  "The snail to sell",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandSell) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/sell").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		query := ""
+		for _, opt := range commandOptions(i) {
+			if opt.Name == "snail" {
+				query = opt.StringValue()
+			}
+		}
+
+		snail, err := models.FindOwnedSnail(state.DB, *user, query)
+		if err != nil {
+			log.WithField("cmd", "/sell").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, query)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+				"You can only sell your own snails.",
+			)
+			return
+		}
+
+		// Snails can't be sold mid race
+		err = state.WhileIdle(func() error {
+			return models.SellSnail(state.DB, *user, *snail)
+		}, snail.ID)
+		switch err {
+		case nil:
+		case models.ErrSnailRacing:
+			log.WithField("cmd", "/sell").Infof("User %s tried to sell racing snail %s", i.Member.User.Username, snail.Name)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but %s is racing", i.Member.User.Username, snail.Name),
+				"Snails can't be sold while they are in a race, try again once the race is over.",
+			)
+			return
+		case models.ErrLastSnail:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you need a snail", i.Member.User.Username), "You can't sell your last snail.")
+			return
+		case models.ErrSnailInEscrow:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but %s is busy", i.Member.User.Username, snail.Name), "The snail is being held for a trade or auction.")
+			return
+		default:
+			log.WithField("cmd", "/sell").WithError(err).Warnf("Error selling %s for %s", snail.Name, i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with selling your snail, please try again.",
+			)
+			return
+		}
+
+		log.WithField("cmd", "/sell").Infof("User %s sold %s", i.Member.User.Username, snail.Name)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("Goodbye %s!", snail.Name),
+			fmt.Sprintf("You sold **%s** to the shop for %dg.", snail.Name, models.SnailSellPrice),
+		)
+	}
+}
+
+func (c *CommandSell) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandSell) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandSell) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
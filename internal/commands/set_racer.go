@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandSetRacer sets the user's default racer, this is the snail that is
+// used when joining via the race buttons or when no snail is given.
+type CommandSetRacer struct{}
+
+func (c *CommandSetRacer) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "set_racer",
+		Description: "Set your default racing snail",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail",
+				Description:  "The snail to race with by default",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandSetRacer) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/set_racer").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		query := ""
+		for _, opt := range commandOptions(i) {
+			if opt.Name == "snail" {
+				query = opt.StringValue()
+			}
+		}
+
+		snail, err := models.FindOwnedSnail(state.DB, *user, query)
+		if err != nil {
+			log.WithField("cmd", "/set_racer").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, query)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+				"You can only set one of your own snails as your racer.",
+			)
+			return
+		}
+
+		if err := models.SetActiveSnail(state.DB, *user, *snail); err != nil {
+			log.WithField("cmd", "/set_racer").WithError(err).Warnf("Error setting active snail for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with setting your racer, please try again.",
+			)
+			return
+		}
+
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("%s is now your racer", snail.Name),
+			fmt.Sprintf("**%s (lvl. %d)** will now race by default with the following stats:\n```\n%s```\n", snail.Name, snail.Level, snail.Stats.RenderStatBlock()),
+		)
+	}
+}
+
+func (c *CommandSetRacer) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandSetRacer) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandSetRacer) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail": autocompleteOwnedSnails(state),
+	}
+}
@@ -0,0 +1,127 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandShellSwap swaps the shells, and so the weight, of two of the user's
+// snails
+type CommandShellSwap struct{}
+
+func (c *CommandShellSwap) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "shell_swap",
+		Description: fmt.Sprintf("Swap the shells of two of your snails, leaving them sad for %d races", models.MoodLockRaces),
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:         "snail_1",
+				Description:  "The first snail",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+			{
+				Name:         "snail_2",
+				Description:  "The second snail",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Required:     true,
+				Autocomplete: true,
+			},
+		},
+	}
+}
+
+func (c *CommandShellSwap) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/shell_swap").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		// Get both of the patients
+		snails := make(map[string]*models.Snail)
+		for _, opt := range commandOptions(i) {
+			snail, err := models.FindOwnedSnail(state.DB, *user, opt.StringValue())
+			if err != nil {
+				log.WithField("cmd", "/shell_swap").WithError(err).Infof("User %s doesn't own snail %s", i.Member.User.Username, opt.StringValue())
+				ResponseEmbedFail(s, i, true,
+					fmt.Sprintf("I'm sorry %s, but we couldn't find that snail", i.Member.User.Username),
+					"You can only swap the shells of your own snails.",
+				)
+				return
+			}
+			snails[opt.Name] = snail
+		}
+		a, b := snails["snail_1"], snails["snail_2"]
+
+		// Nobody is operating on a snail mid race
+		err = state.WhileIdle(func() error {
+			return models.SwapShells(state.DB, *user, a, b)
+		}, a.ID, b.ID)
+		switch err {
+		case nil:
+		case models.ErrSnailRacing:
+			log.WithField("cmd", "/shell_swap").Infof("User %s tried to swap the shell of a racing snail", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but those snails are racing", i.Member.User.Username),
+				"Shells can't be swapped while the snails are in a race, try again once the race is over.",
+			)
+			return
+		case models.ErrSameSnail:
+			log.WithField("cmd", "/shell_swap").WithError(err).Infof("User %s tried to swap %s's shell with itself", i.Member.User.Username, a.Name)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but that's the same snail", i.Member.User.Username),
+				"You need two different snails to swap shells.",
+			)
+			return
+		case models.ErrSnailInEscrow:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but those snails are busy", i.Member.User.Username), "One of the snails is being held for a trade or auction.")
+			return
+		default:
+			log.WithField("cmd", "/shell_swap").WithError(err).Warnf("Error swapping shells for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with swapping the shells, please try again.",
+			)
+			return
+		}
+
+		models.AwardAchievement(s, state.DB, i.ChannelID, user.DiscordID, models.AchievementRipperDoc, 2)
+
+		log.WithField("cmd", "/shell_swap").Infof("User %s swapped the shells of %s and %s", i.Member.User.Username, a.Name, b.Name)
+		ResponseEmbedSuccess(s, i, true,
+			"🐚 Shells Swapped",
+			fmt.Sprintf(
+				"**%s** and **%s** have swapped shells, they aren't happy about it and will be %s for their next %d races.\n\n🐌 **%s**\n```\n%s```\n🐌 **%s**\n```\n%s```",
+				a.Name, b.Name, models.MoodSad.Title(), models.MoodLockRaces, a.Name, a.Stats.RenderStatBlock(), b.Name, b.Stats.RenderStatBlock(),
+			),
+		)
+	}
+}
+
+func (c *CommandShellSwap) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandShellSwap) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandShellSwap) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snail_1": autocompleteOwnedSnails(state),
+		"snail_2": autocompleteOwnedSnails(state),
+	}
+}
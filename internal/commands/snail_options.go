@@ -0,0 +1,152 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// Autocomplete handler that suggests the caller's snails matching what they
+// have typed so far. The choice value is the snail's id so it is unique even
+// if two snails share a name.
+func autocompleteOwnedSnails(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			ResponseAutocomplete(s, i, choices)
+			return
+		}
+
+		snails, err := models.GetAllSnails(state.DB, *user)
+		if err != nil {
+			log.WithField("autocomplete", "snail").WithError(err).Warnf("Error getting snails for user %s", i.Member.User.Username)
+			ResponseAutocomplete(s, i, choices)
+			return
+		}
+
+		typed := strings.ToLower(focusedOptionValue(i))
+		for _, snail := range snails {
+			if !strings.Contains(strings.ToLower(snail.Name), typed) {
+				continue
+			}
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  fmt.Sprintf("%s (lvl. %d)", snail.Name, snail.Level),
+				Value: fmt.Sprintf("%d", snail.ID),
+			})
+		}
+
+		ResponseAutocomplete(s, i, choices)
+	}
+}
+
+// The current value of the option being autocompleted
+func focusedOptionValue(i *discordgo.InteractionCreate) string {
+	for _, opt := range commandOptions(i) {
+		if opt.Focused {
+			return fmt.Sprintf("%v", opt.Value)
+		}
+	}
+	return ""
+}
+
+// Get the snail a user has picked with a `snail` option, if they didn't pick
+// one then fallback to their active snail.
+func getOptionSnail(state *models.State, user *models.User, query string) (*models.Snail, error) {
+	if query == "" {
+		return models.GetActiveSnail(state.DB, *user)
+	}
+	return models.FindOwnedSnail(state.DB, *user, query)
+}
+
+// Autocomplete handler for a comma separated list of snails, owned by the user
+// that owner returns. Everything before the last comma is kept and the last
+// snail is completed, so the value ends up as a list of snail ids.
+func autocompleteSnailList(state *models.State, owner func(i *discordgo.InteractionCreate) string) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+
+		user, err := models.GetUserByDiscordID(state.DB, owner(i))
+		if err != nil {
+			ResponseAutocomplete(s, i, choices)
+			return
+		}
+
+		snails, err := models.GetAllSnails(state.DB, *user)
+		if err != nil {
+			log.WithField("autocomplete", "snails").WithError(err).Warnf("Error getting snails for user %s", user.DiscordID)
+			ResponseAutocomplete(s, i, choices)
+			return
+		}
+
+		// Split off the snails that have already been picked
+		parts := strings.Split(focusedOptionValue(i), ",")
+		typed := strings.ToLower(strings.TrimSpace(parts[len(parts)-1]))
+		picked := make(map[string]bool)
+		for _, part := range parts[:len(parts)-1] {
+			picked[strings.TrimSpace(part)] = true
+		}
+
+		pickedNames, pickedValues := make([]string, 0), make([]string, 0)
+		for _, snail := range snails {
+			if id := fmt.Sprintf("%d", snail.ID); picked[id] || picked[snail.Name] {
+				pickedNames = append(pickedNames, snail.Name)
+				pickedValues = append(pickedValues, id)
+			}
+		}
+
+		for _, snail := range snails {
+			id := fmt.Sprintf("%d", snail.ID)
+			if picked[id] || picked[snail.Name] || snail.InEscrow() || !strings.Contains(strings.ToLower(snail.Name), typed) {
+				continue
+			}
+
+			name := strings.Join(append(pickedNames, fmt.Sprintf("%s (lvl. %d)", snail.Name, snail.Level)), ", ")
+			value := strings.Join(append(pickedValues, id), ",")
+			if len(name) > 100 || len(value) > 100 {
+				continue
+			}
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: value})
+		}
+
+		ResponseAutocomplete(s, i, choices)
+	}
+}
+
+// Get the snails in a comma separated list of snail ids or names, they must
+// all belong to the owner.
+func parseSnailList(state *models.State, owner *models.User, list string) ([]models.Snail, error) {
+	snails := make([]models.Snail, 0)
+	seen := make(map[uint]bool)
+	for _, query := range strings.Split(list, ",") {
+		query = strings.TrimSpace(query)
+		if query == "" {
+			continue
+		}
+
+		snail, err := models.FindOwnedSnail(state.DB, *owner, query)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't find snail %q: %w", query, err)
+		}
+		if !seen[snail.ID] {
+			seen[snail.ID] = true
+			snails = append(snails, *snail)
+		}
+	}
+	return snails, nil
+}
+
+// The value of an option of the subcommand, or an empty string if it wasn't
+// given. This also works while another option is being autocompleted.
+func optionString(i *discordgo.InteractionCreate, name string) string {
+	for _, opt := range commandOptions(i) {
+		if opt.Name == name {
+			return fmt.Sprintf("%v", opt.Value)
+		}
+	}
+	return ""
+}
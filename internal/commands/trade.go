@@ -0,0 +1,335 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// CommandTrade offers another user a trade of snails and money
+type CommandTrade struct{}
+
+func (c *CommandTrade) Decleration() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Name:        "offer",
+		Description: "Offer another user a trade of snails and money",
+		Type:        discordgo.ApplicationCommandOptionSubCommand,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "user",
+				Description: "The user to trade with",
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Required:    true,
+			},
+			{
+				Name:         "snails",
+				Description:  "Your snails to give, separated by commas",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Autocomplete: true,
+			},
+			{
+				Name:        "money",
+				Description: "The money to give",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+			},
+			{
+				Name:         "for_snails",
+				Description:  "Their snails you want, separated by commas",
+				Type:         discordgo.ApplicationCommandOptionString,
+				Autocomplete: true,
+			},
+			{
+				Name:        "for_money",
+				Description: "The money you want",
+				Type:        discordgo.ApplicationCommandOptionInteger,
+			},
+		},
+	}
+}
+
+func (c *CommandTrade) AppHandler(state *models.State) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		// Check if the user is initialised, if the user isn't initialised then
+		// we need to tell them to initialise their account.
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
+		if err != nil {
+			log.WithField("cmd", "/trade").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but you arent initialised", i.Member.User.Username),
+				"You'll need to initialise your account with `/snailrace init` to use this command.",
+			)
+			return
+		}
+
+		var (
+			other                 *discordgo.User
+			snailList, forList    string
+			money, forMoney       int64
+			fromSnails, toSnails  []models.Snail
+			parseErr, forParseErr error
+		)
+		for _, opt := range commandOptions(i) {
+			switch opt.Name {
+			case "user":
+				other = opt.UserValue(s)
+			case "snails":
+				snailList = opt.StringValue()
+			case "money":
+				money = opt.IntValue()
+			case "for_snails":
+				forList = opt.StringValue()
+			case "for_money":
+				forMoney = opt.IntValue()
+			}
+		}
+
+		// Make sure the other user can trade
+		if other == nil || other.ID == i.Member.User.ID {
+			log.WithField("cmd", "/trade").Infof("User %s tried to trade with themselves", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't trade with yourself", i.Member.User.Username), "Pick someone else to trade with.")
+			return
+		}
+		otherUser, err := models.GetUserByDiscordID(state.DB, memberAccountID(state, i, other.ID))
+		if err != nil {
+			log.WithField("cmd", "/trade").WithError(err).Infof("User %s tried to trade with uninitialised user %s", i.Member.User.Username, other.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but %s isn't initialised", i.Member.User.Username, other.Username),
+				fmt.Sprintf("%s will need to initialise their account with `/snailrace init` before you can trade with them.", other.Username),
+			)
+			return
+		}
+
+		// Get the snails on either side
+		fromSnails, parseErr = parseSnailList(state, user, snailList)
+		toSnails, forParseErr = parseSnailList(state, otherUser, forList)
+		if parseErr != nil || forParseErr != nil {
+			log.WithField("cmd", "/trade").Infof("User %s offered a trade with unknown snails", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but we couldn't find those snails", i.Member.User.Username),
+				fmt.Sprintf("You can only offer your own snails, and ask for snails that %s owns.", other.Username),
+			)
+			return
+		}
+		if len(fromSnails)+len(toSnails) > models.MaxTradeSnails {
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but that's too many snails", i.Member.User.Username),
+				fmt.Sprintf("A trade can have up to %d snails in it.", models.MaxTradeSnails),
+			)
+			return
+		}
+
+		// Snails can't change hands mid race, or join one while they're being
+		// put into escrow
+		var trade *models.Trade
+		err = state.WhileIdle(func() (err error) {
+			trade, err = models.CreateTrade(state.DB, *user, *otherUser, fromSnails, money, toSnails, forMoney)
+			return err
+		}, append(snailIDs(fromSnails), snailIDs(toSnails)...)...)
+		switch err {
+		case nil:
+		case models.ErrSnailRacing:
+			log.WithField("cmd", "/trade").Infof("User %s offered a trade with racing snails", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but those snails are racing", i.Member.User.Username),
+				"Snails can't be traded while they are in a race, try again once the race is over.",
+			)
+			return
+		case models.ErrEmptyTrade:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that trade is empty", i.Member.User.Username), "Add some snails or money to either side of the trade.")
+			return
+		case models.ErrInvalidAmount:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that isn't money", i.Member.User.Username), "You can't trade a negative amount of money.")
+			return
+		case models.ErrLastSnail:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you need a snail", i.Member.User.Username), "You can't trade away your last snail.")
+			return
+		case models.ErrSnailInEscrow:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but those snails are busy", i.Member.User.Username), "Some of those snails are already being held for another trade or auction.")
+			return
+		case models.ErrInsufficientFunds:
+			ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't afford that", i.Member.User.Username), fmt.Sprintf("You only have %dg to offer.", user.Money))
+			return
+		default:
+			log.WithField("cmd", "/trade").WithError(err).Warnf("Error creating trade for %s", i.Member.User.Username)
+			ResponseEmbedFail(s, i, true,
+				fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+				"There has been an issue with creating your trade, please try again.",
+			)
+			return
+		}
+
+		if err := models.PostTrade(s, state.DB, trade, i.ChannelID); err != nil {
+			log.WithField("cmd", "/trade").WithError(err).Warnf("Failed to post trade %s", trade.TradeID)
+		}
+
+		log.WithField("cmd", "/trade").Infof("User %s offered trade %s to %s", i.Member.User.Username, trade.TradeID, other.Username)
+		ResponseEmbedSuccess(s, i, true,
+			fmt.Sprintf("Trade `%s` offered", trade.TradeID),
+			fmt.Sprintf("Your side of the trade is being held until %s answers, or the offer expires in %d minutes. You can withdraw the offer with `/snailrace trade deny trade_id: %s`.", other.Username, int(models.TradeTimeout.Minutes()), trade.TradeID),
+		)
+	}
+}
+
+func snailIDs(snails []models.Snail) []uint {
+	ids := make([]uint, 0)
+	for _, snail := range snails {
+		ids = append(ids, snail.ID)
+	}
+	return ids
+}
+
+// Accept a trade for the user, this is shared between the accept command and
+// the accept button so `field` and `name` are used for logging.
+func acceptTrade(s *discordgo.Session, i *discordgo.InteractionCreate, state *models.State, field string, name string, tradeId string) {
+	trade, err := models.GetTrade(state.DB, tradeId)
+	if err != nil {
+		log.WithField(field, name).WithError(err).Infof("User %s tried to accept unknown trade %s", i.Member.User.Username, tradeId)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Trade %s not avaliable", tradeId), "There is no trade with the ID you supplied.")
+		return
+	}
+
+	// None of the snails can join a race while they change hands
+	err = state.WhileIdle(func() error {
+		return models.AcceptTrade(state.DB, trade, accountID(state, i))
+	}, append(trade.SnailIDs(trade.FromID), trade.SnailIDs(trade.ToID)...)...)
+	switch err {
+	case nil:
+	case models.ErrSnailRacing:
+		log.WithField(field, name).Infof("User %s tried to accept trade %s with racing snails", i.Member.User.Username, tradeId)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but those snails are racing", i.Member.User.Username),
+			"Snails can't be traded while they are in a race, try again once the race is over.",
+		)
+		return
+	case models.ErrNotTrader:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that trade isn't for you", i.Member.User.Username), "Only the user the trade was offered to can accept it.")
+		return
+	case models.ErrTradeClosed:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that trade is over", i.Member.User.Username), "The trade has already been answered or has expired.")
+		return
+	case models.ErrLastSnail:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you need a snail", i.Member.User.Username), "You can't trade away your last snail.")
+		return
+	case models.ErrSnailInEscrow:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but those snails are busy", i.Member.User.Username), "Some of your snails in the trade are being held for another trade or auction, or are no longer yours.")
+		return
+	case models.ErrInsufficientFunds:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but you can't afford that", i.Member.User.Username), fmt.Sprintf("You need %dg to accept the trade.", trade.ToMoney))
+		return
+	default:
+		log.WithField(field, name).WithError(err).Warnf("Error accepting trade %s for %s", tradeId, i.Member.User.Username)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+			"There has been an issue with accepting the trade, please try again.",
+		)
+		return
+	}
+
+	log.WithField(field, name).Infof("User %s accepted trade %s", i.Member.User.Username, tradeId)
+	trade.Render(s)
+	for _, trader := range []string{trade.FromID, trade.ToID} {
+		models.AwardAchievement(s, state.DB, i.ChannelID, trader, models.AchievementTrader, 1)
+		models.AwardSnailCount(s, state.DB, i.ChannelID, trader)
+	}
+	ResponseEmbedSuccess(s, i, true, fmt.Sprintf("Trade `%s` accepted", tradeId), "Everything has changed hands, enjoy your new things!")
+}
+
+// Deny or withdraw a trade for the user, this is shared between the deny
+// command and the deny button so `field` and `name` are used for logging.
+func denyTrade(s *discordgo.Session, i *discordgo.InteractionCreate, state *models.State, field string, name string, tradeId string) {
+	trade, err := models.GetTrade(state.DB, tradeId)
+	if err != nil {
+		log.WithField(field, name).WithError(err).Infof("User %s tried to deny unknown trade %s", i.Member.User.Username, tradeId)
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("Trade %s not avaliable", tradeId), "There is no trade with the ID you supplied.")
+		return
+	}
+
+	switch err := models.DenyTrade(state.DB, trade, accountID(state, i)); err {
+	case nil:
+	case models.ErrNotTrader:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that trade isn't yours", i.Member.User.Username), "Only the users in the trade can deny it.")
+		return
+	case models.ErrTradeClosed:
+		ResponseEmbedFail(s, i, true, fmt.Sprintf("I'm sorry %s, but that trade is over", i.Member.User.Username), "The trade has already been answered or has expired.")
+		return
+	default:
+		log.WithField(field, name).WithError(err).Warnf("Error denying trade %s for %s", tradeId, i.Member.User.Username)
+		ResponseEmbedFail(s, i, true,
+			fmt.Sprintf("I'm sorry %s, but there has been an issue", i.Member.User.Username),
+			"There has been an issue with denying the trade, please try again.",
+		)
+		return
+	}
+
+	log.WithField(field, name).Infof("User %s denied trade %s", i.Member.User.Username, tradeId)
+	trade.Render(s)
+	ResponseEmbedInfo(s, i, true, fmt.Sprintf("Trade `%s` called off", tradeId), "Everything held for the trade has been given back.")
+}
+
+// Autocomplete handler that suggests the trades waiting on the caller, only
+// the trades offered to them if incoming is set.
+func autocompletePendingTrades(state *models.State, incoming bool) func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+		choices := make([]*discordgo.ApplicationCommandOptionChoice, 0)
+
+		account := accountID(state, i)
+		trades, err := models.GetPendingTrades(state.DB, account)
+		if err != nil {
+			log.WithField("autocomplete", "trade_id").WithError(err).Warnf("Error getting trades for user %s", i.Member.User.Username)
+			ResponseAutocomplete(s, i, choices)
+			return
+		}
+
+		for _, trade := range trades {
+			if incoming && trade.ToID != account {
+				continue
+			}
+
+			gives, wants := len(trade.SnailIDs(trade.FromID)), len(trade.SnailIDs(trade.ToID))
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  fmt.Sprintf("%s: %d snails and %dg for %d snails and %dg", trade.TradeID, gives, trade.FromMoney, wants, trade.ToMoney),
+				Value: trade.TradeID,
+			})
+		}
+
+		ResponseAutocomplete(s, i, choices)
+	}
+}
+
+func (c *CommandTrade) ActionHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		models.TradeActionAccept: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if len(options) != 1 {
+				log.WithField("interaction", models.TradeActionAccept).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, "Invalid trade", "We couldn't work out which trade that was, please try again.")
+				return
+			}
+			acceptTrade(s, i, state, "interaction", models.TradeActionAccept, options[0])
+		},
+		models.TradeActionDeny: func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+			if len(options) != 1 {
+				log.WithField("interaction", models.TradeActionDeny).Errorf("Not enough arguments/options from user %s", i.Member.User.Username)
+				ResponseEmbedFail(s, i, true, "Invalid trade", "We couldn't work out which trade that was, please try again.")
+				return
+			}
+			denyTrade(s, i, state, "interaction", models.TradeActionDeny, options[0])
+		},
+	}
+}
+
+func (c *CommandTrade) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
+
+func (c *CommandTrade) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
+		"snails": autocompleteSnailList(state, func(i *discordgo.InteractionCreate) string {
+			return accountID(state, i)
+		}),
+		"for_snails": autocompleteSnailList(state, func(i *discordgo.InteractionCreate) string {
+			return memberAccountID(state, i, optionString(i, "user"))
+		}),
+	}
+}
@@ -11,6 +11,9 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// The number of transactions shown in the wallet
+const WalletHistoryLength = 10
+
 // WalletCommand is a simple command that displays the users wallet.
 type WalletCommand struct{}
 
@@ -26,7 +29,7 @@ func (c *WalletCommand) AppHandler(state *models.State) func(s *discordgo.Sessio
 	return func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		// Check if the user is initialised, if the user isn't initialised then
 		// we need to tell them to initialise their account.
-		user, err := models.GetUserByDiscordID(state.DB, i.Member.User.ID)
+		user, err := models.GetUserByDiscordID(state.DB, accountID(state, i))
 		if err != nil {
 			log.WithField("cmd", "/wallet").WithError(err).Infof("User %s is not initialised", i.Member.User.Username)
 			ResponseEmbedFail(s, i, true,
@@ -36,9 +39,22 @@ func (c *WalletCommand) AppHandler(state *models.State) func(s *discordgo.Sessio
 			return
 		}
 
-		// Display the users wallet
+		// Display the users wallet along with their recent transactions
 		p := message.NewPrinter(language.English)
-		ResponseEmbedSuccess(s, i, true, "Wallet", p.Sprintf("💰 %dg", user.Money))
+		body := p.Sprintf("💰 %dg", user.Money)
+
+		entries, err := models.GetRecentLedgerEntries(state.DB, user.DiscordID, WalletHistoryLength)
+		if err != nil {
+			log.WithField("cmd", "/wallet").WithError(err).Warnf("Failed to get transactions for user %s", i.Member.User.Username)
+		}
+		if len(entries) > 0 {
+			body += "\n\n**Recent Transactions:**\n"
+			for _, entry := range entries {
+				body += p.Sprintf("`%+6dg` **%s** %s <t:%d:R>\n", entry.Amount, entry.Kind.Title(), entry.Memo, entry.CreatedAt.Unix())
+			}
+		}
+
+		ResponseEmbedSuccess(s, i, true, "Wallet", body)
 
 	}
 }
@@ -50,3 +66,7 @@ func (c *WalletCommand) ActionHandler(state *models.State, options ...string) ma
 func (c *WalletCommand) ModalHandler(state *models.State, options ...string) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
 }
+
+func (c *WalletCommand) AutocompleteHandler(state *models.State) map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	return map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){}
+}
@@ -10,14 +10,26 @@ import (
 )
 
 func SetupDatabase() (*gorm.DB, error) {
-	// Open Database Connection, or Create Database if it doesn't exist
-	db, err := gorm.Open(sqlite.Open("db/snailrace.db"), &gorm.Config{})
+	// Open Database Connection, or Create Database if it doesn't exist. The
+	// busy timeout and immediate transactions let concurrent handlers queue for
+	// the write lock instead of failing.
+	db, err := gorm.Open(sqlite.Open("db/snailrace.db?_busy_timeout=5000&_txlock=immediate"), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
 
 	// Migrate the schemas
-	return db, MigrateSchemas(db)
+	if err := MigrateSchemas(db); err != nil {
+		return db, err
+	}
+
+	// Snails from before genetics need genes to breed with
+	if err := models.BackfillGenomes(db); err != nil {
+		return db, err
+	}
+
+	// Snails from before weight need one to race with
+	return db, models.BackfillWeights(db)
 }
 
 func MigrateSchemas(db *gorm.DB) error {
@@ -26,6 +38,23 @@ func MigrateSchemas(db *gorm.DB) error {
 	schemas := []interface{}{
 		&models.User{},
 		&models.Snail{},
+		&models.RaceRecord{},
+		&models.RaceRecordEntrant{},
+		&models.RaceRecordBet{},
+		&models.LedgerEntry{},
+		&models.Trade{},
+		&models.TradeSnail{},
+		&models.Auction{},
+		&models.Raffle{},
+		&models.RaffleTicket{},
+		&models.ShopListing{},
+		&models.AchievementProgress{},
+		&models.AdminAction{},
+		&models.UserGuild{},
+		&models.GuildConfig{},
+		&models.Gift{},
+		&models.Hype{},
+		&models.HypeCheer{},
 	}
 
 	// Migrate the schemas
@@ -44,6 +44,23 @@ func SetupDiscord(state *models.State) *discordgo.Session {
 		log.WithError(err).Fatal("Failed opening connection to Discord:", err)
 	}
 
+	// Refund any races that were cut short the last time the bot ran, this
+	// has to happen before the commands are registered so no new races are
+	// mistaken for them
+	models.RecoverRaces(discord, state.DB)
+
+	// Pick up the trades that were waiting on an answer
+	models.ResumeTrades(discord, state.DB)
+
+	// Carry on with the auctions that were still taking bids
+	models.ResumeAuctions(discord, state.DB)
+
+	// Keep watching the crowds that were still gathering
+	models.ResumeHypes(discord, state.DB)
+
+	// Draw the raffles in the background
+	models.StartRaffles(discord, state.DB)
+
 	// Register Commands
 	err = RegisterCommands(state, discord)
 	if err != nil {
@@ -62,7 +79,38 @@ func RegisterCommands(state *models.State, s *discordgo.Session) error {
 		&commands.CommandJoinRace{},
 		&commands.BetCommand{},
 		&commands.WalletCommand{},
+		&commands.CommandBackpack{},
 		&commands.CommandDisplayProfile{},
+		&commands.CommandReplayRace{},
+		&commands.CommandSetRacer{},
+		&commands.CommandBreed{},
+		&commands.CommandShellSwap{},
+		&commands.CommandPedigree{},
+		&commands.CommandGift{},
+		&commands.CommandGroup{
+			Name:        "trade",
+			Description: "Trade snails and money with other users",
+			Commands: []commands.DiscordAppCommand{
+				&commands.CommandTrade{},
+				&commands.CommandAcceptTrade{},
+				&commands.CommandDenyTrade{},
+			},
+		},
+		&commands.CommandGroup{
+			Name:        "market",
+			Description: "Buy and sell snails in the shop and at auction",
+			Commands: []commands.DiscordAppCommand{
+				&commands.CommandBuy{},
+				&commands.CommandSell{},
+				&commands.CommandAuction{},
+				&commands.CommandBid{},
+			},
+		},
+		&commands.CommandRaffle{},
+		&commands.CommandAchievements{},
+		&commands.CommandLeaderboard{},
+		&commands.CommandHype{},
+		&commands.CommandAdmin{},
 	}
 
 	// Create Full decleration
@@ -0,0 +1,333 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type AchievementID string
+
+const (
+	AchievementSnailHoarder  AchievementID = "snail_hoarder"
+	AchievementHypeTrain     AchievementID = "hype_train"
+	AchievementTicketMaster  AchievementID = "ticket_master"
+	AchievementEVTrainer     AchievementID = "ev_trainer"
+	AchievementTrader        AchievementID = "black_market_trader"
+	AchievementJerry         AchievementID = "jerry"
+	AchievementRipperDoc     AchievementID = "ripper_doc"
+	AchievementBigWinner     AchievementID = "big_winner"
+	AchievementPureKindness  AchievementID = "pure_kindness"
+	AchievementAuctioneer    AchievementID = "auctioneer"
+	AchievementHighestBidder AchievementID = "highest_bidder"
+	AchievementSnailMechanic AchievementID = "snail_mechanic"
+
+	AchievementLevels = 5
+)
+
+// Achievement is a badge with five levels, each unlocked when the user's
+// progress reaches its threshold.
+type Achievement struct {
+	ID         AchievementID
+	Name       string
+	Symbol     string
+	Thresholds [AchievementLevels]int64
+	Flavour    [AchievementLevels]string
+}
+
+// Achievements are defined here, see `docs/draft_achievements.md`
+var Achievements = []Achievement{
+	{
+		ID: AchievementSnailHoarder, Name: "Snail Hoarder", Symbol: "📦",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You own 10 snails, I hope you are looking after them.",
+			"You own 25 snails, this seems a bit excessive.",
+			"You own 50 snails, I think you have a problem.",
+			"You own 75 snails, Perhaps you should seek help.",
+			"You own 100 snails now? WHY?!?!",
+		},
+	},
+	{
+		ID: AchievementHypeTrain, Name: "Hype Train", Symbol: "📣",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You've hyped up 10 snails of other people, you're doing good for a junior.",
+			"You've hyped up 25 snails of other people, such comradery.",
+			"You've hyped up 50 snails of other people, I hope you're not getting paid for this.",
+			"You've hyped up 75 snails of other people, LET'S HEAR SOME NOISE!!!!!",
+			"You've hyped up 100 snails of other people. *sips energy drink* LET'S F***ING GO!!!!!!!!!",
+		},
+	},
+	{
+		ID: AchievementTicketMaster, Name: "Ticket Master", Symbol: "🎟️",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You've won 10 raffles, luck of the draw I guess.",
+			"You've won 25 raffles, hmm... seems sus.",
+			"You've won 50 raffles, whats your secret?",
+			"You've won 75 raffles, I'm starting to think you're cheating.",
+			"You've spent way too much money on the raffle, but at least you've won 100 times... Somehow.",
+		},
+	},
+	{
+		ID: AchievementEVTrainer, Name: "The EV Trainer", Symbol: "🧬",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You've bred 10 rank snails. You're a natural.",
+			"You've bred 25 rank snails. Wow, you're really good at this.",
+			"You've bred 50 rank snails. They should get you to try and breed Pandas.",
+			"You've bred 75 rank snails. You're a true master of snail genetics.",
+			"You've bred 100 rank snails. Your dedication to snail genetics is unparalleled.",
+		},
+	},
+	{
+		ID: AchievementTrader, Name: "Black Market Trader", Symbol: "🤝",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You've made 10 trades, I hope you didn't get scammed.",
+			"You've made 25 trades, No seriously, I hope you didn't get scammed.",
+			"You've made 50 trades, but were they all fair trades?",
+			"You've made 75 trades, I'm starting to think you're the scammer.",
+			"You've made 100 trades, I dub you `Licensed Scam Artist`.",
+		},
+	},
+	{
+		ID: AchievementJerry, Name: "Jerry", Symbol: "🤡",
+		Thresholds: [AchievementLevels]int64{1, 2, 3, 5, 10},
+		Flavour: [AchievementLevels]string{
+			"You've lost all your money in single big bet. You muppet, why did you do that?",
+			"You've lost all your money in single big bets 2 times. Really? You did it again?",
+			"You've lost all your money in single big bets 3 times. You're a slow learner.",
+			"You've lost all your money in single big bets 5 times. You aren't a clown, you're the entire circus.",
+			"You've lost all your money in single big bets 10 times. Congrats, Jerry. You're now banned.",
+		},
+	},
+	{
+		ID: AchievementRipperDoc, Name: "Ripper Doc", Symbol: "🐚",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You've swapped shells on 10 snails, I didn't feel a thing.",
+			"You've swapped shells on 25 snails, You know they aren't designed for this.",
+			"You've swapped shells on 50 snails, I hope you're not doing this for fun.",
+			"You've swapped shells on 75 snails, I'm starting to think you're a mad scientist.",
+			"You've swapped shells on 100 snails, I'm calling the RSPCA.",
+		},
+	},
+	{
+		ID: AchievementBigWinner, Name: "Big Winner", Symbol: "🏆",
+		Thresholds: [AchievementLevels]int64{50, 100, 150, 250, 500},
+		Flavour: [AchievementLevels]string{
+			"You've won 50 races, you're doing pretty well for yourself.",
+			"You've won 100 races, you're showing them.",
+			"You've won 150 races, you need to slow down.",
+			"You've won 250 races, the other snails are starting to get suspicious.",
+			"You've won 500 races, it must be lonely at the top.",
+		},
+	},
+	{
+		ID: AchievementPureKindness, Name: "Pure Kindness", Symbol: "🎁",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"You've gifted away 10 snails. You're a good person.",
+			"You've gifted away 25 snails. You're a very good person.",
+			"You've gifted away 50 snails. You must be running a charity.",
+			"You've gifted away 75 snails. You aren't doing this for the achievement are you?",
+			"You've gifted away 100 snails. Where do you get all these snails from?",
+		},
+	},
+	{
+		ID: AchievementAuctioneer, Name: "The Auctioneer", Symbol: "🔨",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"Auction off 10 items. *slam* SOLD!",
+			"Auction off 25 items. Dollar dollar bills y'all.",
+			"Auction off 50 items. I didn't want that anyway.",
+			"Auction off 75 items. I'm starting to think you're a scalper.",
+			"Auction off 100 items. Your Auctioneer hammer must only be a handle at this point.",
+		},
+	},
+	{
+		ID: AchievementHighestBidder, Name: "The Highest Bidder", Symbol: "💰",
+		Thresholds: [AchievementLevels]int64{10, 25, 50, 75, 100},
+		Flavour: [AchievementLevels]string{
+			"Be the highest bidder for 10 auctions. You're a bit of a show off.",
+			"Be the highest bidder for 25 auctions. Want to share some of that money?",
+			"Be the highest bidder for 50 auctions. Want it, bid it, get it.",
+			"Be the highest bidder for 75 auctions. I hope you arent using your parents credit card.",
+			"Be the highest bidder for 100 auctions. Money? What's that?",
+		},
+	},
+	{
+		ID: AchievementSnailMechanic, Name: "Snail Mechanic", Symbol: "🔧",
+		Thresholds: [AchievementLevels]int64{1, 2, 5, 10, 20},
+		Flavour: [AchievementLevels]string{
+			"Congratulations on your first bug fix or feature implementation! Keep up the good work, we're counting on you!",
+			"Two down, three to go! Thanks for your hard work in squashing those bugs and improving our game.",
+			"You've reached a milestone with five bug fixes or feature implementations! Your dedication to making our game better is truly appreciated.",
+			"Double digits! You've made ten bug fixes or feature implementations, and we couldn't be more grateful for your help in improving our game.",
+			"You've hit an impressive 20 bug fixes or feature implementations! Your contributions to our game have been invaluable, and we can't thank you enough for your hard work and dedication.",
+		},
+	},
+}
+
+func GetAchievement(id AchievementID) (Achievement, bool) {
+	for _, achievement := range Achievements {
+		if achievement.ID == id {
+			return achievement, true
+		}
+	}
+	return Achievement{}, false
+}
+
+// The level the progress has reached, 0 if it hasn't reached the first
+func (a Achievement) Level(progress int64) int {
+	level := 0
+	for _, threshold := range a.Thresholds {
+		if progress >= threshold {
+			level++
+		}
+	}
+	return level
+}
+
+// Render the level as a bar, e.g. `[###  ]`
+func RenderAchievementLevel(level int) string {
+	return fmt.Sprintf("[%s%s]", strings.Repeat("#", level), strings.Repeat(" ", AchievementLevels-level))
+}
+
+// AchievementProgress is how far a user is towards each level of an
+// achievement, it only ever goes up.
+type AchievementProgress struct {
+	gorm.Model
+
+	UserDiscordID string        `gorm:"uniqueIndex:idx_achievement_user"`
+	Achievement   AchievementID `gorm:"uniqueIndex:idx_achievement_user"`
+	Progress      int64
+	Level         int
+}
+
+// AchievementUnlock is a new level of an achievement the user has reached
+type AchievementUnlock struct {
+	UserDiscordID string
+	Achievement   Achievement
+	Level         int
+}
+
+func GetAchievementProgress(db *gorm.DB, discordID string) (map[AchievementID]AchievementProgress, error) {
+	progress := []AchievementProgress{}
+	if err := db.Where("user_discord_id = ?", discordID).Find(&progress).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[AchievementID]AchievementProgress)
+	for _, entry := range progress {
+		byID[entry.Achievement] = entry
+	}
+	return byID, nil
+}
+
+// AddAchievementProgress adds to the user's progress towards the achievement,
+// returning the levels unlocked by it.
+func AddAchievementProgress(db *gorm.DB, discordID string, id AchievementID, amount int64) ([]AchievementUnlock, error) {
+	return updateAchievement(db, discordID, id, gorm.Expr("progress + ?", amount), amount)
+}
+
+// SetAchievementProgress raises the user's progress towards the achievement
+// to the value if it is higher, for achievements that count what the user has
+// rather than what they have done.
+func SetAchievementProgress(db *gorm.DB, discordID string, id AchievementID, value int64) ([]AchievementUnlock, error) {
+	return updateAchievement(db, discordID, id, gorm.Expr("MAX(progress, ?)", value), value)
+}
+
+func updateAchievement(db *gorm.DB, discordID string, id AchievementID, progress clause.Expr, initial int64) ([]AchievementUnlock, error) {
+	log.Debugf("updateAchievement(id: %s, achievement: %s)", discordID, id)
+
+	achievement, ok := GetAchievement(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown achievement %s", id)
+	}
+
+	unlocks := make([]AchievementUnlock, 0)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		entry := &AchievementProgress{UserDiscordID: discordID, Achievement: id, Progress: initial}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_discord_id"}, {Name: "achievement"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"progress": progress}),
+		}).Create(entry).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("user_discord_id = ? AND achievement = ?", discordID, id).First(entry).Error; err != nil {
+			return err
+		}
+
+		// Only the levels that weren't already unlocked are new
+		level := achievement.Level(entry.Progress)
+		if level <= entry.Level {
+			return nil
+		}
+		for unlocked := entry.Level + 1; unlocked <= level; unlocked++ {
+			unlocks = append(unlocks, AchievementUnlock{UserDiscordID: discordID, Achievement: achievement, Level: unlocked})
+		}
+		return tx.Model(entry).Update("level", level).Error
+	})
+	return unlocks, err
+}
+
+// Keep the snail hoarder achievement up to date with how many snails the user
+// owns, this should be called whenever the user gets a new snail.
+func CountSnailsAchievement(db *gorm.DB, discordID string) ([]AchievementUnlock, error) {
+	var count int64
+	if err := db.Model(&Snail{}).Where("owner_id = ?", discordID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	return SetAchievementProgress(db, discordID, AchievementSnailHoarder, count)
+}
+
+// AwardAchievement adds to the user's achievement progress and announces any
+// levels it unlocks in the channel. Achievements are only cosmetic so any
+// errors are logged rather than returned.
+func AwardAchievement(s *discordgo.Session, db *gorm.DB, channelId string, discordID string, id AchievementID, amount int64) {
+	unlocks, err := AddAchievementProgress(db, discordID, id, amount)
+	if err != nil {
+		log.WithField("achievement", id).WithError(err).Warnf("Failed to update achievement for %s", discordID)
+		return
+	}
+	AnnounceAchievements(s, channelId, unlocks)
+}
+
+// AwardSnailCount is AwardAchievement for the snail hoarder achievement
+func AwardSnailCount(s *discordgo.Session, db *gorm.DB, channelId string, discordID string) {
+	unlocks, err := CountSnailsAchievement(db, discordID)
+	if err != nil {
+		log.WithField("achievement", AchievementSnailHoarder).WithError(err).Warnf("Failed to update achievement for %s", discordID)
+		return
+	}
+	AnnounceAchievements(s, channelId, unlocks)
+}
+
+// Announce each unlocked level in the channel it was unlocked in
+func AnnounceAchievements(s *discordgo.Session, channelId string, unlocks []AchievementUnlock) {
+	if s == nil || channelId == "" {
+		return
+	}
+
+	for _, unlock := range unlocks {
+		log.WithField("achievement", unlock.Achievement.ID).Infof("User %s unlocked level %d", unlock.UserDiscordID, unlock.Level)
+
+		content := fmt.Sprintf("> **%s %s `%s`**\n> %s\n\n%s",
+			unlock.Achievement.Symbol, unlock.Achievement.Name, RenderAchievementLevel(unlock.Level),
+			unlock.Achievement.Flavour[unlock.Level-1], Mention(unlock.UserDiscordID),
+		)
+		if _, err := s.ChannelMessageSend(channelId, content); err != nil {
+			log.WithField("achievement", unlock.Achievement.ID).WithError(err).Warn("Failed to announce achievement")
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestAchievementLevelsUnlockOnce(t *testing.T) {
+	db := newTestDB(t)
+
+	unlocks, err := AddAchievementProgress(db, "user", AchievementJerry, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unlocks) != 3 || unlocks[2].Level != 3 {
+		t.Errorf("unlocked %d levels at 3 progress, expected 3", len(unlocks))
+	}
+
+	unlocks, _ = AddAchievementProgress(db, "user", AchievementJerry, 1)
+	if len(unlocks) != 0 {
+		t.Errorf("unlocked %d levels without reaching a threshold", len(unlocks))
+	}
+	unlocks, _ = AddAchievementProgress(db, "user", AchievementJerry, 1)
+	if len(unlocks) != 1 || unlocks[0].Level != 4 {
+		t.Errorf("expected level 4 to unlock at 5 progress, got %v", unlocks)
+	}
+
+	// Counted achievements never go backwards
+	SetAchievementProgress(db, "user", AchievementSnailHoarder, 12)
+	SetAchievementProgress(db, "user", AchievementSnailHoarder, 3)
+	progress, err := GetAchievementProgress(db, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := progress[AchievementSnailHoarder]; got.Progress != 12 || got.Level != 1 {
+		t.Errorf("snail hoarder is at %d progress and level %d, expected 12 and 1", got.Progress, got.Level)
+	}
+	if got := progress[AchievementJerry]; got.Progress != 5 || got.Level != 4 {
+		t.Errorf("jerry is at %d progress and level %d, expected 5 and 4", got.Progress, got.Level)
+	}
+}
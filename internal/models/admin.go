@@ -0,0 +1,107 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// AdminAction is an entry in the audit log, every admin command is recorded
+// along with who used it.
+type AdminAction struct {
+	gorm.Model
+
+	AdminID  string `gorm:"index"`
+	Action   string `gorm:"index"`
+	TargetID string `gorm:"index"`
+	Details  string
+}
+
+func RecordAdminAction(db *gorm.DB, adminID string, action string, targetID string, details string) error {
+	log.WithField("admin", adminID).Infof("Admin %s on %s: %s", action, targetID, details)
+
+	entry := &AdminAction{AdminID: adminID, Action: action, TargetID: targetID, Details: details}
+	return db.Create(entry).Error
+}
+
+func GetRecentAdminActions(db *gorm.DB, limit int) ([]AdminAction, error) {
+	actions := []AdminAction{}
+	result := db.Order("id desc").Limit(limit).Find(&actions)
+	return actions, result.Error
+}
+
+// GrantAchievement gives the user a point of progress towards the achievement,
+// this is how the achievements that can't be earned in game are given out.
+func GrantAchievement(db *gorm.DB, adminID string, discordID string, id AchievementID) ([]AchievementUnlock, error) {
+	var unlocks []AchievementUnlock
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if unlocks, err = AddAchievementProgress(tx, discordID, id, 1); err != nil {
+			return err
+		}
+		return RecordAdminAction(tx, adminID, "grant", discordID, string(id))
+	})
+	return unlocks, err
+}
+
+// RevokeAchievement takes a point of progress towards the achievement away
+// from the user, which can take away a level.
+func RevokeAchievement(db *gorm.DB, adminID string, discordID string, id AchievementID) error {
+	achievement, ok := GetAchievement(id)
+	if !ok {
+		return fmt.Errorf("unknown achievement %s", id)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		entry := &AchievementProgress{}
+		if err := tx.Where("user_discord_id = ? AND achievement = ?", discordID, id).First(entry).Error; err != nil {
+			return err
+		}
+
+		if entry.Progress > 0 {
+			entry.Progress--
+		}
+		entry.Level = achievement.Level(entry.Progress)
+		if err := tx.Model(entry).Select("Progress", "Level").Updates(entry).Error; err != nil {
+			return err
+		}
+		return RecordAdminAction(tx, adminID, "revoke", discordID, string(id))
+	})
+}
+
+// AdjustBalance gives money to or takes money from the user through the
+// ledger, a negative amount takes money.
+func AdjustBalance(db *gorm.DB, adminID string, discordID string, amount int64, reason string) (*LedgerEntry, error) {
+	var entry *LedgerEntry
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if amount < 0 {
+			entry, err = Debit(tx, discordID, -amount, LedgerAdmin, reason)
+		} else {
+			entry, err = Credit(tx, discordID, amount, LedgerAdmin, reason)
+		}
+		if err != nil {
+			return err
+		}
+		return RecordAdminAction(tx, adminID, "money", discordID, fmt.Sprintf("%+dg: %s", amount, reason))
+	})
+	return entry, err
+}
+
+// SpawnSnail creates a new snail for the user at the level
+func SpawnSnail(db *gorm.DB, adminID string, owner User, level SnailStatLevel) (*Snail, error) {
+	var snail *Snail
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if snail, err = CreateSnail(tx, owner, level); err != nil {
+			return err
+		}
+		if err := ensureActiveSnail(tx, owner.DiscordID); err != nil {
+			return err
+		}
+		return RecordAdminAction(tx, adminID, "spawn", owner.DiscordID, fmt.Sprintf("%s snail %s", level.Title(), snail.Name))
+	})
+	return snail, err
+}
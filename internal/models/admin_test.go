@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestAdminActionsAreAudited(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "user", 10)
+
+	if _, err := AdjustBalance(db, "admin", "user", 15, "prize"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AdjustBalance(db, "admin", "user", -100, "fine"); err != ErrInsufficientFunds {
+		t.Errorf("took more money than the user has, got %v", err)
+	}
+
+	// Mechanic level 1 is a single grant, and revoking it takes it away
+	unlocks, err := GrantAchievement(db, "admin", "user", AchievementSnailMechanic)
+	if err != nil || len(unlocks) != 1 {
+		t.Fatalf("granting unlocked %d levels, got %v", len(unlocks), err)
+	}
+	if err := RevokeAchievement(db, "admin", "user", AchievementSnailMechanic); err != nil {
+		t.Fatal(err)
+	}
+	progress, _ := GetAchievementProgress(db, "user")
+	if got := progress[AchievementSnailMechanic]; got.Level != 0 {
+		t.Errorf("snail mechanic is level %d after being revoked", got.Level)
+	}
+
+	// The failed adjustment changed nothing, so it isn't in the log
+	actions, _ := GetRecentAdminActions(db, 10)
+	if len(actions) != 3 || actions[0].Action != "revoke" || actions[2].Action != "money" {
+		t.Errorf("audit log has %d actions, expected money, grant and revoke", len(actions))
+	}
+}
@@ -0,0 +1,363 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type AuctionStatus string
+
+const (
+	AuctionStatusOpen   AuctionStatus = "open"
+	AuctionStatusSold   AuctionStatus = "sold"
+	AuctionStatusUnsold AuctionStatus = "unsold"
+
+	// Auction Constants
+	AuctionTimeout    = 10 * time.Minute // How long an auction waits for a first bid
+	AuctionBidTimeout = 1 * time.Minute  // How long an auction runs after the last bid
+	AuctionMinRaise   = 1                // How much a bid has to beat the high bid by
+)
+
+var (
+	ErrAuctionNotFound = fmt.Errorf("auction not found")
+	ErrAuctionClosed   = fmt.Errorf("auction is over")
+	ErrOwnAuction      = fmt.Errorf("can't bid on your own auction")
+	ErrBidTooLow       = fmt.Errorf("bid is too low")
+
+	errAuctionRunning = fmt.Errorf("auction hasn't ended yet")
+)
+
+// Auction is a snail up for sale to the highest bidder. The snail is held in
+// escrow while the auction is open, and so is the money of the high bidder.
+type Auction struct {
+	gorm.Model
+
+	AuctionID string `gorm:"uniqueIndex"`
+	ChannelID string
+	MessageID string
+
+	SellerID      string `gorm:"index"`
+	SnailID       uint
+	Snail         Snail
+	StartingPrice int64
+
+	HighBid      int64
+	HighBidderID string
+	Bids         int
+
+	Status AuctionStatus `gorm:"index"`
+	EndsAt time.Time
+}
+
+func (a *Auction) escrowTag() string {
+	return escrowTag("auction", a.AuctionID)
+}
+
+// The least a new bid can be
+func (a *Auction) MinimumBid() int64 {
+	if a.HighBidderID == "" {
+		return a.StartingPrice
+	}
+	return a.HighBid + AuctionMinRaise
+}
+
+// CreateAuction puts the seller's snail up for auction, holding it in escrow
+// until the auction is over.
+func CreateAuction(db *gorm.DB, seller User, snail Snail, startingPrice int64) (*Auction, error) {
+	log.Debugf("CreateAuction(seller: %s, snail: %d, price: %d)", seller.DiscordID, snail.ID, startingPrice)
+
+	if startingPrice <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	auction := &Auction{
+		SellerID:      seller.DiscordID,
+		SnailID:       snail.ID,
+		StartingPrice: startingPrice,
+		Status:        AuctionStatusOpen,
+		EndsAt:        time.Now().Add(AuctionTimeout),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		auction.AuctionID = uuid.New().String()[24:]
+
+		keeps, err := keepsASnail(tx, seller.DiscordID, 1, 0)
+		if err != nil {
+			return err
+		}
+		if !keeps {
+			return ErrLastSnail
+		}
+
+		if err := escrowSnails(tx, seller.DiscordID, []uint{snail.ID}, auction.escrowTag()); err != nil {
+			return err
+		}
+		return tx.Omit("Snail").Create(auction).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetAuction(db, auction.AuctionID)
+}
+
+func GetAuction(db *gorm.DB, auctionId string) (*Auction, error) {
+	log.Debugf("GetAuction(auction: %s)", auctionId)
+
+	auction := &Auction{}
+	result := db.Where("auction_id = ?", auctionId).
+		Preload("Snail", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(auction)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrAuctionNotFound
+	}
+	return auction, result.Error
+}
+
+// The auctions that are still taking bids, ending soonest first
+func GetOpenAuctions(db *gorm.DB) ([]Auction, error) {
+	auctions := []Auction{}
+	result := db.Where("status = ?", AuctionStatusOpen).
+		Preload("Snail").
+		Order("ends_at asc").
+		Find(&auctions)
+	return auctions, result.Error
+}
+
+// PlaceAuctionBid makes the bidder the high bidder, holding their money in
+// escrow and giving the previous high bidder theirs back.
+func PlaceAuctionBid(db *gorm.DB, auction *Auction, bidderID string, amount int64) error {
+	log.Debugf("PlaceAuctionBid(auction: %s, bidder: %s, amount: %d)", auction.AuctionID, bidderID, amount)
+
+	if bidderID == auction.SellerID {
+		return ErrOwnAuction
+	}
+	if !SameEconomy(bidderID, auction.SellerID) {
+		return ErrAuctionNotFound
+	}
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		current := &Auction{}
+		if err := tx.First(current, auction.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != AuctionStatusOpen || !time.Now().Before(current.EndsAt) {
+			return ErrAuctionClosed
+		}
+		if amount < current.MinimumBid() {
+			return ErrBidTooLow
+		}
+
+		if _, err := Debit(tx, bidderID, amount, LedgerEscrow, fmt.Sprintf("Bid on auction %s", current.AuctionID)); err != nil {
+			return err
+		}
+		if current.HighBidderID != "" {
+			memo := fmt.Sprintf("Outbid on auction %s", current.AuctionID)
+			if _, err := Credit(tx, current.HighBidderID, current.HighBid, LedgerEscrow, memo); err != nil {
+				return err
+			}
+		}
+
+		// Only take the bid if nobody else got in first
+		endsAt := time.Now().Add(AuctionBidTimeout)
+		result := tx.Model(&Auction{}).
+			Where("id = ? AND status = ? AND bids = ?", current.ID, AuctionStatusOpen, current.Bids).
+			Updates(map[string]interface{}{
+				"high_bid":       amount,
+				"high_bidder_id": bidderID,
+				"bids":           current.Bids + 1,
+				"ends_at":        endsAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBidTooLow
+		}
+
+		auction.HighBid, auction.HighBidderID, auction.Bids, auction.EndsAt = amount, bidderID, current.Bids+1, endsAt
+		return nil
+	})
+}
+
+// FinishAuction ends an auction that has run its time. The snail goes to the
+// high bidder and their money to the seller, or back to the seller if nobody
+// bid on it.
+func FinishAuction(db *gorm.DB, auction *Auction) error {
+	log.Debugf("FinishAuction(auction: %s)", auction.AuctionID)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		current := &Auction{}
+		if err := tx.First(current, auction.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != AuctionStatusOpen {
+			return ErrAuctionClosed
+		}
+		if time.Now().Before(current.EndsAt) {
+			return errAuctionRunning
+		}
+
+		status := AuctionStatusUnsold
+		if current.HighBidderID != "" {
+			status = AuctionStatusSold
+		}
+
+		result := tx.Model(&Auction{}).
+			Where("id = ? AND status = ? AND bids = ?", current.ID, AuctionStatusOpen, current.Bids).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errAuctionRunning
+		}
+
+		if status == AuctionStatusUnsold {
+			if err := releaseSnails(tx, current.escrowTag()); err != nil {
+				return err
+			}
+		} else {
+			if err := transferSnails(tx, []uint{current.SnailID}, current.escrowTag(), current.HighBidderID); err != nil {
+				return err
+			}
+			memo := fmt.Sprintf("Sold at auction %s", current.AuctionID)
+			if _, err := Credit(tx, current.SellerID, current.HighBid, LedgerAuction, memo); err != nil {
+				return err
+			}
+			if err := ensureActiveSnail(tx, current.SellerID); err != nil {
+				return err
+			}
+		}
+
+		auction.HighBid, auction.HighBidderID, auction.Bids, auction.EndsAt = current.HighBid, current.HighBidderID, current.Bids, current.EndsAt
+		auction.Status = status
+		return nil
+	})
+}
+
+// PostAuction sends the auction to the channel and then runs it until it is
+// over.
+func PostAuction(s *discordgo.Session, db *gorm.DB, auction *Auction, channelId string) error {
+	message, err := s.ChannelMessageSendComplex(channelId, &discordgo.MessageSend{
+		Embeds: []*discordgo.MessageEmbed{auction.embed()},
+	})
+	if err != nil {
+		return err
+	}
+
+	auction.ChannelID, auction.MessageID = channelId, message.ID
+	if err := db.Model(auction).Updates(map[string]interface{}{"channel_id": channelId, "message_id": message.ID}).Error; err != nil {
+		return err
+	}
+
+	goBackground(func(ctx context.Context) { RunAuction(ctx, s, db, auction.AuctionID) })
+	return nil
+}
+
+// RunAuction waits for the auction to end and then finishes it. Every bid
+// pushes the end back, so the auction is reloaded after each wait to see if
+// it has really ended. All of the state lives in the database, so this can
+// pick up an auction from before a restart.
+func RunAuction(ctx context.Context, s *discordgo.Session, db *gorm.DB, auctionId string) {
+	for {
+		auction, err := GetAuction(db, auctionId)
+		if err != nil {
+			log.WithField("auction", auctionId).WithError(err).Warn("Failed to get auction to run")
+			return
+		}
+		if auction.Status != AuctionStatusOpen {
+			return
+		}
+
+		if wait := time.Until(auction.EndsAt); wait > 0 {
+			if !sleepBackground(ctx, wait) {
+				return
+			}
+			continue
+		}
+
+		switch err := FinishAuction(db, auction); err {
+		case nil:
+			log.WithField("auction", auctionId).Infof("Auction is %s", auction.Status)
+			auction.Render(s)
+			if auction.Status == AuctionStatusSold {
+				AwardAchievement(s, db, auction.ChannelID, auction.SellerID, AchievementAuctioneer, 1)
+				AwardAchievement(s, db, auction.ChannelID, auction.HighBidderID, AchievementHighestBidder, 1)
+				AwardSnailCount(s, db, auction.ChannelID, auction.HighBidderID)
+			}
+			return
+		case errAuctionRunning, ErrAuctionClosed:
+		default:
+			log.WithField("auction", auctionId).WithError(err).Error("Failed to finish auction")
+			return
+		}
+	}
+}
+
+// ResumeAuctions runs the auctions that were still open when the bot stopped,
+// any that ended in the meantime are finished straight away.
+func ResumeAuctions(s *discordgo.Session, db *gorm.DB) {
+	auctions := []Auction{}
+	if err := db.Where("status = ?", AuctionStatusOpen).Find(&auctions).Error; err != nil {
+		log.WithError(err).Error("Failed to find open auctions")
+		return
+	}
+
+	for _, auction := range auctions {
+		auctionId := auction.AuctionID
+		goBackground(func(ctx context.Context) { RunAuction(ctx, s, db, auctionId) })
+	}
+}
+
+// Render the auction message as it currently stands
+func (a *Auction) Render(s *discordgo.Session) {
+	if a.MessageID == "" {
+		return
+	}
+
+	edit := discordgo.NewMessageEdit(a.ChannelID, a.MessageID)
+	edit.Embeds = []*discordgo.MessageEmbed{a.embed()}
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		log.WithField("auction", a.AuctionID).WithError(err).Warn("Failed to render auction")
+	}
+}
+
+func (a *Auction) embed() *discordgo.MessageEmbed {
+	body := fmt.Sprintf("%s is auctioning 🐌 **%s** (lvl. %d)\n", Mention(a.SellerID), a.Snail.Name, a.Snail.Level)
+	body += fmt.Sprintf("```\nSpeed: %.1f, Stamina: %.1f, Recovery: %.1f, Weight: %.1f\n```\n", a.Snail.Stats.Speed, a.Snail.Stats.Stamina, a.Snail.Stats.Recovery, a.Snail.Stats.Weight)
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Auction `%s`", a.AuctionID),
+		Color: 0x3498db,
+	}
+
+	switch a.Status {
+	case AuctionStatusOpen:
+		if a.HighBidderID == "" {
+			body += fmt.Sprintf("No bids yet, bidding starts at 💰 %dg.\n", a.StartingPrice)
+		} else {
+			body += fmt.Sprintf("The high bid is 💰 %dg by %s (%d bids).\n", a.HighBid, Mention(a.HighBidderID), a.Bids)
+		}
+		body += fmt.Sprintf("The auction ends <t:%d:R>, bid with:\n```\n/snailrace market bid auction_id: %s money: %d\n```", a.EndsAt.Unix(), a.AuctionID, a.MinimumBid())
+	case AuctionStatusSold:
+		body += fmt.Sprintf("Sold to %s for 💰 %dg!", Mention(a.HighBidderID), a.HighBid)
+		embed.Color = 0x2ecc71
+	case AuctionStatusUnsold:
+		body += "Nobody bid on the snail, so it has gone back to its owner."
+		embed.Color = 0xe74c3c
+	}
+
+	embed.Description = body
+	return embed
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuctionRefundsOutbidAndPaysSeller(t *testing.T) {
+	db := newTestDB(t)
+	for _, id := range []string{"seller", "first", "second"} {
+		newTestUser(t, db, id, 100)
+	}
+	seller, _ := GetUserByDiscordID(db, "seller")
+	snails := []Snail{newTestOwnedSnail(t, db, "seller"), newTestOwnedSnail(t, db, "seller")}
+
+	auction, err := CreateAuction(db, *seller, snails[0], 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := PlaceAuctionBid(db, auction, "seller", 50); err != ErrOwnAuction {
+		t.Errorf("seller bid on their own auction, got %v", err)
+	}
+	if err := PlaceAuctionBid(db, auction, "first", 10); err != ErrBidTooLow {
+		t.Errorf("bid under the starting price, got %v", err)
+	}
+	if err := PlaceAuctionBid(db, auction, "first", 30); err != nil {
+		t.Fatal(err)
+	}
+	if err := PlaceAuctionBid(db, auction, "second", 30); err != ErrBidTooLow {
+		t.Errorf("matched the high bid, got %v", err)
+	}
+	if err := PlaceAuctionBid(db, auction, "second", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// It can't finish until a minute after the last bid
+	if err := FinishAuction(db, auction); err != errAuctionRunning {
+		t.Errorf("finished an auction that is still running, got %v", err)
+	}
+	db.Model(&Auction{}).Where("id = ?", auction.ID).Update("ends_at", time.Now())
+	if err := FinishAuction(db, auction); err != nil {
+		t.Fatal(err)
+	}
+	if err := PlaceAuctionBid(db, auction, "first", 100); err != ErrAuctionClosed {
+		t.Errorf("bid on a finished auction, got %v", err)
+	}
+
+	var snail Snail
+	db.First(&snail, snails[0].ID)
+	if snail.OwnerID != "second" || snail.InEscrow() {
+		t.Errorf("auctioned snail is owned by %q with escrow %q", snail.OwnerID, snail.Escrow)
+	}
+
+	// Everyone started with 110g
+	for id, want := range map[string]int64{"seller": 170, "first": 110, "second": 50} {
+		user, _ := GetUserByDiscordID(db, id)
+		if user.Money != want {
+			t.Errorf("%s has %dg, expected %dg", id, user.Money, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// The trade, auction and hype timers and the raffle draw all run in the
+// background against the database. They are stopped and waited on before the
+// database is closed, everything they wait for is stored so they pick up
+// where they left off after a restart.
+var background = struct {
+	mu      sync.Mutex
+	ctx     context.Context
+	stop    context.CancelFunc
+	running sync.WaitGroup
+	stopped bool
+}{}
+
+func init() {
+	background.ctx, background.stop = context.WithCancel(context.Background())
+}
+
+// Run the task in the background, tasks started after the background has been
+// stopped never run.
+func goBackground(task func(ctx context.Context)) {
+	background.mu.Lock()
+	defer background.mu.Unlock()
+
+	if background.stopped {
+		return
+	}
+
+	background.running.Add(1)
+	go func() {
+		defer background.running.Done()
+		task(background.ctx)
+	}()
+}
+
+// Sleep for the duration, returns false if the background was stopped first
+func sleepBackground(ctx context.Context, duration time.Duration) bool {
+	if duration <= 0 {
+		return ctx.Err() == nil
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// StopBackground stops the background tasks and waits for them to return, a
+// task part way through a database update gets to finish it.
+func StopBackground() {
+	background.mu.Lock()
+	background.stopped = true
+	background.stop()
+	background.mu.Unlock()
+
+	background.running.Wait()
+}
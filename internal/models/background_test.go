@@ -0,0 +1,33 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSleepBackgroundStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	if !sleepBackground(ctx, time.Millisecond) {
+		t.Error("sleep was stopped early")
+	}
+	if !sleepBackground(ctx, -time.Minute) {
+		t.Error("sleep in the past was stopped")
+	}
+
+	stopped := make(chan bool)
+	go func() { stopped <- sleepBackground(ctx, time.Hour) }()
+	cancel()
+
+	select {
+	case ok := <-stopped:
+		if ok {
+			t.Error("stopped sleep reported finishing")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sleep didn't stop")
+	}
+	if sleepBackground(ctx, 0) {
+		t.Error("sleep after stopping reported finishing")
+	}
+}
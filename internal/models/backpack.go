@@ -0,0 +1,93 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type BackpackSort string
+type BackpackFilter string
+
+const (
+	BackpackSortLevel BackpackSort = "level"
+	BackpackSortSpeed BackpackSort = "speed"
+	BackpackSortWins  BackpackSort = "wins"
+
+	BackpackFilterAll    BackpackFilter = "all"
+	BackpackFilterReady  BackpackFilter = "ready" // Not held in escrow
+	BackpackFilterEscrow BackpackFilter = "escrow"
+	BackpackFilterBred   BackpackFilter = "bred"
+
+	BackpackPageSize = 5
+)
+
+var (
+	BackpackSorts   = []BackpackSort{BackpackSortLevel, BackpackSortSpeed, BackpackSortWins}
+	BackpackFilters = []BackpackFilter{BackpackFilterAll, BackpackFilterReady, BackpackFilterEscrow, BackpackFilterBred}
+)
+
+// A short human description of the sort
+func (sort BackpackSort) Title() string {
+	switch sort {
+	case BackpackSortSpeed:
+		return "Fastest first"
+	case BackpackSortWins:
+		return "Most wins first"
+	}
+	return "Highest level first"
+}
+
+func (sort BackpackSort) order() string {
+	switch sort {
+	case BackpackSortSpeed:
+		return "speed desc, id asc"
+	case BackpackSortWins:
+		return "wins desc, races asc, id asc"
+	}
+	return "level desc, exp desc, id asc"
+}
+
+// A short human description of the filter
+func (filter BackpackFilter) Title() string {
+	switch filter {
+	case BackpackFilterReady:
+		return "Ready to race"
+	case BackpackFilterEscrow:
+		return "Held for a trade or auction"
+	case BackpackFilterBred:
+		return "Bred"
+	}
+	return "All snails"
+}
+
+func (filter BackpackFilter) scope(db *gorm.DB) *gorm.DB {
+	switch filter {
+	case BackpackFilterReady:
+		return db.Where("escrow = ''")
+	case BackpackFilterEscrow:
+		return db.Where("escrow != ''")
+	case BackpackFilterBred:
+		return db.Where("sire_id > 0")
+	}
+	return db
+}
+
+// GetBackpackPage gets a page of the owner's snails, along with how many
+// snails match the filter in total.
+func GetBackpackPage(db *gorm.DB, owner User, sort BackpackSort, filter BackpackFilter, page int) ([]Snail, int64, error) {
+	log.Debugf("GetBackpackPage(owner: %s, sort: %s, filter: %s, page: %d)", owner.DiscordID, sort, filter, page)
+
+	var total int64
+	if err := filter.scope(db.Model(&Snail{}).Where("owner_id = ?", owner.DiscordID)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	snails := []Snail{}
+	result := filter.scope(db.Where("owner_id = ?", owner.DiscordID)).
+		Order(sort.order()).
+		Offset(page * BackpackPageSize).
+		Limit(BackpackPageSize).
+		Find(&snails)
+	return snails, total, result.Error
+}
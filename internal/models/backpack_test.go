@@ -0,0 +1,34 @@
+package models
+
+import "testing"
+
+func TestBackpackPagesSortAndFilter(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	owner, _ := GetUserByDiscordID(db, "owner")
+	for index := 0; index < BackpackPageSize+2; index++ {
+		snail := Snail{Name: "snail", OwnerID: "owner", Level: uint64(index + 1), Wins: uint64(10 - index)}
+		if index == 0 {
+			snail.Escrow = "trade:test"
+		}
+		db.Create(&snail)
+	}
+
+	snails, total, err := GetBackpackPage(db, *owner, BackpackSortLevel, BackpackFilterAll, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != BackpackPageSize+2 || len(snails) != BackpackPageSize || snails[0].Level != BackpackPageSize+2 {
+		t.Errorf("first page has %d of %d snails, starting at level %d", len(snails), total, snails[0].Level)
+	}
+
+	snails, _, _ = GetBackpackPage(db, *owner, BackpackSortWins, BackpackFilterAll, 1)
+	if len(snails) != 2 || snails[1].Wins != 10-BackpackPageSize-1 {
+		t.Errorf("last page by wins is %v", snails)
+	}
+
+	snails, total, _ = GetBackpackPage(db, *owner, BackpackSortLevel, BackpackFilterEscrow, 0)
+	if total != 1 || len(snails) != 1 || !snails[0].InEscrow() {
+		t.Errorf("escrow filter found %d snails", total)
+	}
+}
@@ -0,0 +1,247 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type BetType string
+
+const (
+	BetWin      BetType = "win"
+	BetPlace    BetType = "place"
+	BetShow     BetType = "show"
+	BetExacta   BetType = "exacta"
+	BetTrifecta BetType = "trifecta"
+
+	// Exotic bets are priced from the win probabilities with a margin for the
+	// house, so they don't print money.
+	ExoticBetMargin = 0.9
+)
+
+var BetTypes = []BetType{BetWin, BetPlace, BetShow, BetExacta, BetTrifecta}
+
+func ParseBetType(str string) (BetType, bool) {
+	for _, t := range BetTypes {
+		if string(t) == str {
+			return t, true
+		}
+	}
+	return BetWin, false
+}
+
+func (t BetType) Title() string {
+	return strings.ToUpper(string(t[:1])) + string(t[1:])
+}
+
+// Describe what the bet needs to win
+func (t BetType) Description() string {
+	switch t {
+	case BetPlace:
+		return "Your snail finishes in the top 2"
+	case BetShow:
+		return "Your snail finishes in the top 3"
+	case BetExacta:
+		return "Pick the exact 1st and 2nd"
+	case BetTrifecta:
+		return "Pick the exact 1st, 2nd and 3rd"
+	}
+	return "Your snail wins the race"
+}
+
+// Number of snails that need to be picked for the bet
+func (t BetType) Picks() int {
+	switch t {
+	case BetExacta:
+		return 2
+	case BetTrifecta:
+		return 3
+	}
+	return 1
+}
+
+// For single pick bets, the place the snail needs to finish in or better
+func (t BetType) places() int {
+	switch t {
+	case BetPlace:
+		return 2
+	case BetShow:
+		return 3
+	}
+	return 1
+}
+
+// Selections are stored and passed around in custom ids as `1-4-2`
+func FormatSelections(selections []int) string {
+	parts := make([]string, len(selections))
+	for index, selection := range selections {
+		parts[index] = strconv.Itoa(selection)
+	}
+	return strings.Join(parts, "-")
+}
+
+func ParseSelections(str string) ([]int, error) {
+	selections := make([]int, 0)
+	if str == "" {
+		return selections, nil
+	}
+
+	for _, part := range strings.Split(str, "-") {
+		selection, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", part)
+		}
+		selections = append(selections, selection)
+	}
+	return selections, nil
+}
+
+// Check the selections are valid for the bet type and race
+func (r *Race) validSelections(betType BetType, selections []int) bool {
+	if len(selections) != betType.Picks() {
+		return false
+	}
+
+	seen := make(map[int]bool)
+	for _, selection := range selections {
+		if selection < 0 || selection >= len(r.Snails) || seen[selection] {
+			return false
+		}
+		seen[selection] = true
+	}
+	return true
+}
+
+// The range of finishing places a snail covers. Tied snails share a position,
+// so two snails tied for first both cover places 1 to 2 and the next snail
+// finishes 3rd.
+func (r *Race) placeRange(index int) (int, int) {
+	position := r.racePosPosition(r.Snails[index])
+	if position == 0 {
+		return 0, 0
+	}
+
+	better, tied := 0, 0
+	for _, p := range r.Winners {
+		if p.Position < position {
+			better++
+		} else if p.Position == position {
+			tied++
+		}
+	}
+	return better + 1, better + tied
+}
+
+// Check if the bet has won. For exotic bets each pick needs to cover its
+// place, so in a dead heat either order of the tied snails wins.
+func (r *Race) betWon(bet RaceBet) bool {
+	if !r.validSelections(bet.Type, bet.Selections) {
+		return false
+	}
+
+	switch bet.Type {
+	case BetExacta, BetTrifecta:
+		for place, selection := range bet.Selections {
+			start, end := r.placeRange(selection)
+			if start == 0 || place+1 < start || place+1 > end {
+				return false
+			}
+		}
+		return true
+	}
+
+	start, _ := r.placeRange(bet.Selections[0])
+	return start != 0 && start <= bet.Type.places()
+}
+
+// The fixed odds of a bet. Win bets use the snail's odds while the exotic
+// bets are priced from the chance of that outcome using the win odds.
+func (r *Race) BetOdds(betType BetType, selections []int) float64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.betOdds(betType, selections)
+}
+
+func (r *Race) betOdds(betType BetType, selections []int) float64 {
+	if !r.validSelections(betType, selections) || len(r.Odds) != len(r.Snails) {
+		return 0
+	}
+	if betType == BetWin {
+		return r.Odds[selections[0]]
+	}
+
+	probs := r.winProbabilities()
+	chance := 0.0
+	switch betType {
+	case BetPlace, BetShow:
+		chance = topChance(probs, selections[0], betType.places())
+	default:
+		chance = orderChance(probs, selections)
+	}
+
+	if chance <= 0 {
+		return 0
+	}
+	return math.Max(1.0, ExoticBetMargin/chance)
+}
+
+// Convert the win odds into the chance of each snail winning, lower odds are
+// more likely to win.
+func (r *Race) winProbabilities() []float64 {
+	probs, sum := make([]float64, len(r.Odds)), 0.0
+	for index, odd := range r.Odds {
+		probs[index] = 1.0 / math.Max(odd, 1.0)
+		sum += probs[index]
+	}
+	for index := range probs {
+		probs[index] /= sum
+	}
+	return probs
+}
+
+// The chance of the snails finishing in exactly this order, using the Harville
+// model where each place is won in proportion to the snails that are left.
+func orderChance(probs []float64, order []int) float64 {
+	chance, remaining := 1.0, 1.0
+	for _, index := range order {
+		if remaining <= 0 {
+			return 0
+		}
+		chance *= probs[index] / remaining
+		remaining -= probs[index]
+	}
+	return chance
+}
+
+// The chance of the snail finishing in the top number of places
+func topChance(probs []float64, snail int, places int) float64 {
+	places = int(math.Min(float64(places), float64(len(probs))))
+	chance := 0.0
+
+	var walk func(order []int, used map[int]bool)
+	walk = func(order []int, used map[int]bool) {
+		if len(order) == places {
+			for _, index := range order {
+				if index == snail {
+					chance += orderChance(probs, order)
+					return
+				}
+			}
+			return
+		}
+
+		for index := range probs {
+			if used[index] {
+				continue
+			}
+			used[index] = true
+			walk(append(order, index), used)
+			used[index] = false
+		}
+	}
+	walk(make([]int, 0, places), make(map[int]bool))
+
+	return chance
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBetsWithTies(t *testing.T) {
+	// Tied snails share a position the same way the simulation places them
+	tiedSecond := []int{1, 2, 2, 3}
+	tiedThird := []int{1, 2, 3, 3}
+
+	for _, test := range []struct {
+		name       string
+		positions  []int
+		betType    BetType
+		selections []int
+		want       bool
+	}{
+		{"win on the tied 2nd", tiedSecond, BetWin, []int{1}, false},
+		{"place on the 1st tied 2nd", tiedSecond, BetPlace, []int{1}, true},
+		{"place on the 2nd tied 2nd", tiedSecond, BetPlace, []int{2}, true},
+		{"show on the tied 2nd", tiedSecond, BetShow, []int{2}, true},
+		{"show after a tie for 2nd", tiedSecond, BetShow, []int{3}, false},
+		{"exacta with the 1st tied 2nd", tiedSecond, BetExacta, []int{0, 1}, true},
+		{"exacta with the 2nd tied 2nd", tiedSecond, BetExacta, []int{0, 2}, true},
+		{"exacta of the tied snails", tiedSecond, BetExacta, []int{1, 2}, false},
+		{"exacta in reverse", tiedSecond, BetExacta, []int{1, 0}, false},
+		{"trifecta with the tie in order", tiedSecond, BetTrifecta, []int{0, 1, 2}, true},
+		{"trifecta with the tie swapped", tiedSecond, BetTrifecta, []int{0, 2, 1}, true},
+		{"trifecta past the tie", tiedSecond, BetTrifecta, []int{0, 1, 3}, false},
+
+		{"place on the tied 3rd", tiedThird, BetPlace, []int{2}, false},
+		{"show on the 1st tied 3rd", tiedThird, BetShow, []int{2}, true},
+		{"show on the 2nd tied 3rd", tiedThird, BetShow, []int{3}, true},
+		{"exacta before the tie", tiedThird, BetExacta, []int{0, 1}, true},
+		{"trifecta with the 1st tied 3rd", tiedThird, BetTrifecta, []int{0, 1, 2}, true},
+		{"trifecta with the 2nd tied 3rd", tiedThird, BetTrifecta, []int{0, 1, 3}, true},
+		{"trifecta with the tie too early", tiedThird, BetTrifecta, []int{0, 3, 1}, false},
+		{"trifecta picking a snail twice", tiedThird, BetTrifecta, []int{0, 1, 1}, false},
+	} {
+		race := newTestFinishedRace(test.positions...)
+		bet := RaceBet{Type: test.betType, Selections: test.selections, Amount: 10}
+		if got := race.betWon(bet); got != test.want {
+			t.Errorf("%s: %s bet on %v won %t, want %t", test.name, test.betType, test.selections, got, test.want)
+		}
+	}
+}
+
+func TestExoticOddsMargin(t *testing.T) {
+	race := newTestFinishedRace(1, 2, 3, 4)
+	race.Odds = []float64{2, 3, 6, 8}
+	probs := race.winProbabilities()
+
+	// Every possible bet, along with the chance of it winning
+	type outcome struct {
+		betType    BetType
+		selections []int
+		chance     float64
+	}
+	outcomes := make([]outcome, 0)
+	for a := range race.Snails {
+		outcomes = append(outcomes,
+			outcome{BetPlace, []int{a}, topChance(probs, a, 2)},
+			outcome{BetShow, []int{a}, topChance(probs, a, 3)},
+		)
+		for b := range race.Snails {
+			if b == a {
+				continue
+			}
+			outcomes = append(outcomes, outcome{BetExacta, []int{a, b}, orderChance(probs, []int{a, b})})
+			for c := range race.Snails {
+				if c == a || c == b {
+					continue
+				}
+				outcomes = append(outcomes, outcome{BetTrifecta, []int{a, b, c}, orderChance(probs, []int{a, b, c})})
+			}
+		}
+	}
+
+	// Each ordering is an outcome of the Harville model, so they cover every
+	// finish exactly once
+	totals := make(map[BetType]float64)
+	for _, o := range outcomes {
+		totals[o.betType] += o.chance
+
+		// A bet returns the margin on average, unless it is so likely that
+		// it only gets its stake back
+		odds := race.betOdds(o.betType, o.selections)
+		if odds < 1 {
+			t.Errorf("%s bet on %v has odds of %v, less than the stake", o.betType, o.selections, odds)
+		}
+		if odds > 1 && math.Abs(odds*o.chance-ExoticBetMargin) > 1e-9 {
+			t.Errorf("%s bet on %v returns %v on average, want %v", o.betType, o.selections, odds*o.chance, ExoticBetMargin)
+		}
+	}
+	for betType, want := range map[BetType]float64{BetPlace: 2, BetShow: 3, BetExacta: 1, BetTrifecta: 1} {
+		if math.Abs(totals[betType]-want) > 1e-9 {
+			t.Errorf("%s outcomes have a total chance of %v, want %v", betType, totals[betType], want)
+		}
+	}
+}
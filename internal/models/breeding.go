@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Breeding Constants
+	BreedingCost   = 50
+	BreedingMinAge = 14 * 24 * time.Hour
+
+	// Each stat has a chance of mutating by up to the mutation range, the
+	// child's genes decide which way it leans. Stats are kept between the min
+	// and max
+	MutationChance = 0.3
+	MutationRange  = 3.0
+	MinStat        = 1.0
+	MaxStat        = 20.0
+)
+
+var (
+	ErrSameSnail = fmt.Errorf("can't pair a snail with itself")
+	ErrTooYoung  = fmt.Errorf("snail is too young to breed")
+)
+
+// How old the snail is, snails are born when they are created
+func (s Snail) Age() time.Duration {
+	return time.Since(s.CreatedAt)
+}
+
+// Whether the snail is old enough to breed
+func (s Snail) CanBreed() bool {
+	return s.Age() >= BreedingMinAge
+}
+
+func (s Snail) IsBred() bool {
+	return s.SireID != 0 || s.DamID != 0
+}
+
+// Inherit each stat from a random blend of the parents, with a chance of the
+// stat mutating. Both parents having a strong stat is the surest way to pass
+// it on, but a lucky mutation can beat them both. The child's genes decide
+// which way the mutations lean, see Genome.mutationRange.
+func InheritStats(sire SnailStats, dam SnailStats, genome Genome) SnailStats {
+	return SnailStats{
+		Speed:    inheritStat(sire.Speed, dam.Speed, genome, LocusSpeed),
+		Stamina:  inheritStat(sire.Stamina, dam.Stamina, genome, LocusStamina),
+		Recovery: inheritStat(sire.Recovery, dam.Recovery, genome, LocusRecovery),
+		Weight:   inheritWeight(sire.Weight, dam.Weight),
+	}
+}
+
+// Weight has no genes behind it, so it blends and mutates either way
+func inheritWeight(sire float64, dam float64) float64 {
+	blend := rand.Float64()
+	weight := sire*blend + dam*(1-blend)
+
+	if rand.Float64() < MutationChance {
+		weight += randFloat64(-MutationRange, MutationRange)
+	}
+
+	return math.Max(MinStat, math.Min(MaxStat, weight))
+}
+
+func inheritStat(sire float64, dam float64, genome Genome, locus Locus) float64 {
+	blend := rand.Float64()
+	stat := sire*blend + dam*(1-blend)
+
+	if rand.Float64() < MutationChance {
+		stat += randFloat64(genome.mutationRange(locus))
+	}
+
+	return math.Max(MinStat, math.Min(MaxStat, stat))
+}
+
+// BreedSnails creates a new snail for the owner from two of their snails. The
+// breeding cost is taken in the same transaction so the owner can't breed
+// without paying for it. Neither parent can be in escrow.
+func BreedSnails(db *gorm.DB, owner User, sire *Snail, dam *Snail) (*Snail, error) {
+	log.Debugf("BreedSnails(owner: %s, sire: %d, dam: %d)", owner.DiscordID, sire.ID, dam.ID)
+
+	if sire.ID == dam.ID {
+		return nil, ErrSameSnail
+	}
+	if sire.OwnerID != owner.DiscordID || dam.OwnerID != owner.DiscordID {
+		return nil, ErrInvalidSnail
+	}
+	if !sire.CanBreed() || !dam.CanBreed() {
+		return nil, ErrTooYoung
+	}
+
+	genome := InheritGenome(sire.Genome, dam.Genome)
+	snail := &Snail{
+		Name:      generateSnailName(),
+		OwnerID:   owner.DiscordID,
+		Level:     1,
+		Stats:     InheritStats(sire.Stats, dam.Stats, genome),
+		Genome:    genome,
+		SireID:    sire.ID,
+		DamID:     dam.ID,
+		BreederID: owner.DiscordID,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The parents may have been offered up since they were looked up
+		var parents int64
+		result := tx.Model(&Snail{}).Where("id IN ? AND owner_id = ? AND escrow = ''", []uint{sire.ID, dam.ID}, owner.DiscordID).Count(&parents)
+		if result.Error != nil {
+			return result.Error
+		}
+		if parents != 2 {
+			return ErrSnailInEscrow
+		}
+
+		memo := fmt.Sprintf("Bred %s and %s", sire.Name, dam.Name)
+		if _, err := Debit(tx, owner.DiscordID, BreedingCost, LedgerBreeding, memo); err != nil {
+			return err
+		}
+		return tx.Create(snail).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return snail, nil
+}
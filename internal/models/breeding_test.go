@@ -0,0 +1,139 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func newTestParent(t *testing.T, db *gorm.DB, owner string, age time.Duration) *Snail {
+	snail := &Snail{Name: "parent", OwnerID: owner, Level: 1, Genome: RandomGenome()}
+	snail.CreatedAt = time.Now().Add(-age)
+	snail.Stats.GenerateStats(StartingSnail)
+	if err := db.Create(snail).Error; err != nil {
+		t.Fatal(err)
+	}
+	return snail
+}
+
+func TestBreedSnails(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", BreedingCost)
+	newTestUser(t, db, "other", 1)
+	owner, _ := GetUserByDiscordID(db, "owner")
+	sire := newTestParent(t, db, "owner", BreedingMinAge+time.Hour)
+	dam := newTestParent(t, db, "owner", BreedingMinAge+time.Hour)
+
+	young := newTestParent(t, db, "owner", BreedingMinAge-time.Hour)
+	stranger := newTestParent(t, db, "other", BreedingMinAge+time.Hour)
+	escrowed := newTestParent(t, db, "owner", BreedingMinAge+time.Hour)
+	db.Model(escrowed).Update("escrow", "trade:test")
+
+	for name, test := range map[string]struct {
+		sire, dam *Snail
+		want      error
+	}{
+		"same snail": {sire, sire, ErrSameSnail},
+		"too young":  {sire, young, ErrTooYoung},
+		"not owned":  {stranger, dam, ErrInvalidSnail},
+		"in escrow":  {sire, escrowed, ErrSnailInEscrow},
+	} {
+		if _, err := BreedSnails(db, *owner, test.sire, test.dam); err != test.want {
+			t.Errorf("%s: got %v, want %v", name, err, test.want)
+		}
+	}
+
+	child, err := BreedSnails(db, *owner, sire, dam)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.SireID != sire.ID || child.DamID != dam.ID || child.BreederID != "owner" || child.OwnerID != "owner" {
+		t.Errorf("child has sire %d, dam %d, breeder %q and owner %q", child.SireID, child.DamID, child.BreederID, child.OwnerID)
+	}
+	if !child.Genome.Valid() {
+		t.Errorf("child has genome %q", child.Genome)
+	}
+	if child.CanBreed() {
+		t.Error("newborn child can breed")
+	}
+
+	// Only the successful breed was paid for, and the owner can't afford another
+	owner, _ = GetUserByDiscordID(db, "owner")
+	if owner.Money != 10 {
+		t.Errorf("owner has %dg after breeding, want 10g", owner.Money)
+	}
+	if _, err := BreedSnails(db, *owner, sire, dam); err != ErrInsufficientFunds {
+		t.Errorf("bred without the funds, got %v", err)
+	}
+	var snails int64
+	db.Model(&Snail{}).Where("sire_id = ?", sire.ID).Count(&snails)
+	if snails != 1 {
+		t.Errorf("%d children were bred, want 1", snails)
+	}
+}
+
+func TestBreedSnailsRollsBackPayment(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", BreedingCost)
+	owner, _ := GetUserByDiscordID(db, "owner")
+	sire := newTestParent(t, db, "owner", BreedingMinAge)
+	dam := newTestParent(t, db, "owner", BreedingMinAge)
+
+	// The child can't be saved after the breeding has been paid for
+	err := db.Callback().Create().Before("gorm:create").Register("fail_snails", func(tx *gorm.DB) {
+		if tx.Statement.Table == "snails" {
+			tx.AddError(fmt.Errorf("no room for the child"))
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BreedSnails(db, *owner, sire, dam); err == nil {
+		t.Fatal("bred a child that couldn't be saved")
+	}
+	owner, _ = GetUserByDiscordID(db, "owner")
+	if owner.Money != 10+BreedingCost {
+		t.Errorf("owner has %dg after a failed breed, want %dg", owner.Money, 10+BreedingCost)
+	}
+	var entries int64
+	db.Model(&LedgerEntry{}).Where("kind = ?", LedgerBreeding).Count(&entries)
+	if entries != 0 {
+		t.Errorf("failed breed left %d ledger entries", entries)
+	}
+}
+
+func TestInheritStatsBounds(t *testing.T) {
+	strong := SnailStats{Speed: MaxStat, Stamina: MaxStat, Recovery: MaxStat, Weight: MaxStat}
+	weak := SnailStats{Speed: MinStat, Stamina: MinStat, Recovery: MinStat, Weight: MinStat}
+	middling := SnailStats{Speed: 8, Stamina: 10, Recovery: 12, Weight: 10}
+
+	for _, genome := range []Genome{"SSTTRRCCPP", "SsTtRrCcPp", "ssttrrccpp"} {
+		for n := 0; n < 1000; n++ {
+			for _, child := range []SnailStats{
+				InheritStats(strong, strong, genome),
+				InheritStats(weak, weak, genome),
+				InheritStats(strong, weak, genome),
+			} {
+				for _, stat := range []float64{child.Speed, child.Stamina, child.Recovery, child.Weight} {
+					if stat < MinStat || stat > MaxStat {
+						t.Fatalf("genome %s inherited stat %.2f", genome, stat)
+					}
+				}
+			}
+		}
+	}
+
+	// Dominant genes keep the stats within a point of the parents
+	for n := 0; n < 1000; n++ {
+		child := InheritStats(middling, middling, "SSTTRRCCPP")
+		for _, stat := range [][2]float64{{child.Speed, 8}, {child.Stamina, 10}, {child.Recovery, 12}} {
+			if math.Abs(stat[0]-stat[1]) > 1 {
+				t.Fatalf("dominant stat %.2f strayed from parents with %.2f", stat[0], stat[1])
+			}
+		}
+	}
+}
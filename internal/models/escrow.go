@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSnailInEscrow = fmt.Errorf("snail is in escrow")
+	ErrLastSnail     = fmt.Errorf("can't give away your last snail")
+)
+
+// Held while a snail joins a race and while snails are escrowed, so a snail
+// can't join a race part way through being traded or sold. See State.WhileIdle.
+var committingSnails sync.Mutex
+
+func (s Snail) InEscrow() bool {
+	return s.Escrow != ""
+}
+
+// The escrow tag for something holding snails, like "trade:1a2b3c"
+func escrowTag(kind string, id string) string {
+	return fmt.Sprintf("%s:%s", kind, id)
+}
+
+// Put the owner's snails into escrow under the tag. This fails with
+// ErrSnailInEscrow if any of them are already held or aren't the owner's.
+func escrowSnails(tx *gorm.DB, ownerID string, ids []uint, tag string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	result := tx.Model(&Snail{}).
+		Where("id IN ? AND owner_id = ? AND escrow = ''", ids, ownerID).
+		Update("escrow", tag)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(ids)) {
+		return ErrSnailInEscrow
+	}
+	return nil
+}
+
+// Give the snails held under the tag back to their owners
+func releaseSnails(tx *gorm.DB, tag string) error {
+	return tx.Model(&Snail{}).Where("escrow = ?", tag).Update("escrow", "").Error
+}
+
+// Hand the snails held under the tag over to their new owner, they won't be
+// the new owner's racer until they pick them.
+func transferSnails(tx *gorm.DB, ids []uint, tag string, ownerID string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	result := tx.Model(&Snail{}).
+		Where("id IN ? AND escrow = ?", ids, tag).
+		Updates(map[string]interface{}{"owner_id": ownerID, "escrow": "", "active": false})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(ids)) {
+		return ErrSnailInEscrow
+	}
+	return nil
+}
+
+// Make sure the owner still has a racer after their snails have changed hands
+func ensureActiveSnail(tx *gorm.DB, ownerID string) error {
+	var count int64
+	if err := tx.Model(&Snail{}).Where("owner_id = ? AND active = ?", ownerID, true).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	snail := &Snail{}
+	if err := tx.Where("owner_id = ?", ownerID).Order("level desc").First(snail).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return err
+	}
+	return tx.Model(snail).Update("active", true).Error
+}
+
+// Whether the owner would still have a snail after giving some away and
+// receiving others
+func keepsASnail(tx *gorm.DB, ownerID string, giving int, receiving int) (bool, error) {
+	var count int64
+	if err := tx.Model(&Snail{}).Where("owner_id = ?", ownerID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count-int64(giving)+int64(receiving) > 0, nil
+}
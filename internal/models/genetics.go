@@ -0,0 +1,215 @@
+package models
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Genome is a snail's hidden genes, two alleles for each locus written as a
+// pair of letters. The uppercase letter is the dominant allele and lowercase
+// is recessive, so a recessive trait only shows when both alleles are
+// recessive and carriers look like any other snail.
+//
+//	"SsTTrrCcpp" is speed carrier, stamina dominant, gifted recovery, shell
+//	colour carrier and spiral pattern.
+type Genome string
+
+type Locus int
+
+const (
+	LocusSpeed Locus = iota
+	LocusStamina
+	LocusRecovery
+	LocusShell
+	LocusPattern
+)
+
+type Genotype uint8
+
+const (
+	GenotypeDominant  Genotype = iota // Two dominant alleles
+	GenotypeCarrier                   // One of each, looks dominant
+	GenotypeRecessive                 // Two recessive alleles
+)
+
+const (
+	// Chance of an allele being recessive for snails that weren't bred
+	RecessiveAlleleChance = 0.25
+
+	// Chance of each inherited allele flipping
+	AlleleMutationChance = 0.02
+
+	// Snails with a gifted stat start with a bonus to that stat
+	GiftedStatMinBonus = 1.0
+	GiftedStatMaxBonus = 3.0
+)
+
+// The letter for each locus, in genome order
+var genomeLoci = []byte{'S', 'T', 'R', 'C', 'P'}
+
+// A random genome for a snail that wasn't bred
+func RandomGenome() Genome {
+	genome := make([]byte, 0, len(genomeLoci)*2)
+	for _, letter := range genomeLoci {
+		genome = append(genome, randomAllele(letter), randomAllele(letter))
+	}
+	return normaliseGenome(genome)
+}
+
+func randomAllele(letter byte) byte {
+	if rand.Float64() < RecessiveAlleleChance {
+		return toRecessive(letter)
+	}
+	return letter
+}
+
+// The child gets one allele from each parent at every locus, with a small
+// chance of the allele mutating.
+func InheritGenome(sire Genome, dam Genome) Genome {
+	sire, dam = sire.orRandom(), dam.orRandom()
+
+	genome := make([]byte, 0, len(genomeLoci)*2)
+	for locus := range genomeLoci {
+		for _, parent := range []Genome{sire, dam} {
+			allele := parent[locus*2+rand.Intn(2)]
+			if rand.Float64() < AlleleMutationChance {
+				allele = flipAllele(allele)
+			}
+			genome = append(genome, allele)
+		}
+	}
+	return normaliseGenome(genome)
+}
+
+// Dominant alleles are written first so the same genes always read the same
+func normaliseGenome(genome []byte) Genome {
+	for index := 0; index < len(genome); index += 2 {
+		if isRecessive(genome[index]) && !isRecessive(genome[index+1]) {
+			genome[index], genome[index+1] = genome[index+1], genome[index]
+		}
+	}
+	return Genome(genome)
+}
+
+func isRecessive(allele byte) bool {
+	return allele >= 'a' && allele <= 'z'
+}
+
+func toRecessive(allele byte) byte {
+	return strings.ToLower(string(allele))[0]
+}
+
+func flipAllele(allele byte) byte {
+	if isRecessive(allele) {
+		return strings.ToUpper(string(allele))[0]
+	}
+	return toRecessive(allele)
+}
+
+func (g Genome) Valid() bool {
+	if len(g) != len(genomeLoci)*2 {
+		return false
+	}
+	for locus, letter := range genomeLoci {
+		for _, allele := range []byte{g[locus*2], g[locus*2+1]} {
+			if allele != letter && allele != toRecessive(letter) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Snails from before genomes existed are given random genes
+func (g Genome) orRandom() Genome {
+	if !g.Valid() {
+		return RandomGenome()
+	}
+	return g
+}
+
+func (g Genome) Genotype(locus Locus) Genotype {
+	if !g.Valid() {
+		return GenotypeDominant
+	}
+
+	recessive := 0
+	for _, allele := range []byte{g[locus*2], g[locus*2+1]} {
+		if isRecessive(allele) {
+			recessive++
+		}
+	}
+	return Genotype(recessive)
+}
+
+// The range a stat can mutate by when breeding. Gifted (recessive) stats tend
+// to mutate upwards while dominant stats barely mutate at all, carriers
+// mutate either way.
+func (g Genome) mutationRange(locus Locus) (float64, float64) {
+	switch g.Genotype(locus) {
+	case GenotypeRecessive:
+		return -1.0, MutationRange + 1.0
+	case GenotypeDominant:
+		return -1.0, 1.0
+	}
+	return -MutationRange, MutationRange
+}
+
+// Gives newly generated snails a bonus to their gifted stats
+func (g Genome) expressStats(stats *SnailStats) {
+	for locus, stat := range map[Locus]*float64{
+		LocusSpeed:    &stats.Speed,
+		LocusStamina:  &stats.Stamina,
+		LocusRecovery: &stats.Recovery,
+	} {
+		if g.Genotype(locus) == GenotypeRecessive {
+			*stat = math.Min(MaxStat, *stat+randFloat64(GiftedStatMinBonus, GiftedStatMaxBonus))
+		}
+	}
+}
+
+// The visible traits of the genome, carriers don't show anything
+func (g Genome) Traits() []string {
+	traits := make([]string, 0)
+	if g.Genotype(LocusSpeed) == GenotypeRecessive {
+		traits = append(traits, "Gifted sprinter")
+	}
+	if g.Genotype(LocusStamina) == GenotypeRecessive {
+		traits = append(traits, "Gifted stayer")
+	}
+	if g.Genotype(LocusRecovery) == GenotypeRecessive {
+		traits = append(traits, "Quick healer")
+	}
+	if g.Genotype(LocusShell) == GenotypeRecessive {
+		traits = append(traits, "Golden shell")
+	}
+	if g.Genotype(LocusPattern) == GenotypeRecessive {
+		traits = append(traits, "Spiral pattern")
+	}
+	return traits
+}
+
+// BackfillGenomes gives every snail without a genome a random one, so their
+// genes don't change every time they breed.
+func BackfillGenomes(db *gorm.DB) error {
+	snails := []Snail{}
+	if err := db.Where("genome = '' OR genome IS NULL").Find(&snails).Error; err != nil {
+		return err
+	}
+
+	for _, snail := range snails {
+		if err := db.Model(&snail).Update("genome", RandomGenome()).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(snails) > 0 {
+		log.Infof("Gave %d snails a genome", len(snails))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNormaliseGenome(t *testing.T) {
+	for genome, want := range map[string]Genome{
+		"SSTTRRCCPP": "SSTTRRCCPP",
+		"sSTtrRcCpP": "SsTtRrCcPp",
+		"ssttrrccpp": "ssttrrccpp",
+		"sSTTrrCCpP": "SsTTrrCCPp",
+	} {
+		if got := normaliseGenome([]byte(genome)); got != want {
+			t.Errorf("%s normalised to %s, want %s", genome, got, want)
+		}
+	}
+}
+
+func TestMalformedGenomes(t *testing.T) {
+	for _, genome := range []Genome{"", "SsTt", "SSTTRRCCPPS", "XXTTRRCCPP", "TTSSRRCCPP", "SSTTRR CCP"} {
+		if genome.Valid() {
+			t.Errorf("%q is valid", genome)
+		}
+		if genome.Genotype(LocusSpeed) != GenotypeDominant {
+			t.Errorf("%q expresses a recessive speed", genome)
+		}
+		if !genome.orRandom().Valid() {
+			t.Errorf("%q wasn't replaced with a valid genome", genome)
+		}
+		if child := InheritGenome(genome, "ssttrrccpp"); !child.Valid() {
+			t.Errorf("%q bred an invalid genome %q", genome, child)
+		}
+	}
+}
+
+func TestMendelianInheritance(t *testing.T) {
+	rand.Seed(1)
+
+	const children = 2000
+	for _, test := range []struct {
+		name      string
+		sire, dam Genome
+		want      [3]float64 // Dominant, carrier and recessive ratios
+	}{
+		{"dominant and recessive", "SSTTRRCCPP", "ssttrrccpp", [3]float64{0, 1, 0}},
+		{"two carriers", "SsTtRrCcPp", "SsTtRrCcPp", [3]float64{0.25, 0.5, 0.25}},
+		{"carrier and recessive", "SsTtRrCcPp", "ssttrrccpp", [3]float64{0, 0.5, 0.5}},
+		{"two recessive", "ssttrrccpp", "ssttrrccpp", [3]float64{0, 0, 1}},
+	} {
+		counts := [3]float64{}
+		for n := 0; n < children; n++ {
+			child := InheritGenome(test.sire, test.dam)
+			if !child.Valid() {
+				t.Fatalf("%s bred an invalid genome %q", test.name, child)
+			}
+			for locus := range genomeLoci {
+				counts[child.Genotype(Locus(locus))]++
+			}
+		}
+
+		// Mutations nudge the ratios by a few percent
+		for genotype, count := range counts {
+			ratio := count / float64(children*len(genomeLoci))
+			if ratio < test.want[genotype]-0.05 || ratio > test.want[genotype]+0.05 {
+				t.Errorf("%s bred genotype %d %.2f of the time, want %.2f", test.name, genotype, ratio, test.want[genotype])
+			}
+		}
+	}
+}
+
+func TestMutationRange(t *testing.T) {
+	for genome, want := range map[Genome][2]float64{
+		"SSTTRRCCPP": {-1.0, 1.0},
+		"SsTTRRCCPP": {-MutationRange, MutationRange},
+		"ssTTRRCCPP": {-1.0, MutationRange + 1.0},
+		"":           {-1.0, 1.0},
+	} {
+		if min, max := genome.mutationRange(LocusSpeed); min != want[0] || max != want[1] {
+			t.Errorf("%q mutates speed between %.1f and %.1f, want %.1f and %.1f", genome, min, max, want[0], want[1])
+		}
+	}
+}
+
+func TestExpressStats(t *testing.T) {
+	for n := 0; n < 1000; n++ {
+		stats := SnailStats{Speed: 5, Stamina: 5, Recovery: MaxStat}
+		Genome("ssTtrrCCPP").expressStats(&stats)
+
+		if stats.Speed < 5+GiftedStatMinBonus || stats.Speed > 5+GiftedStatMaxBonus {
+			t.Fatalf("gifted speed expressed as %.2f", stats.Speed)
+		}
+		if stats.Stamina != 5 {
+			t.Fatalf("carried stamina expressed as %.2f", stats.Stamina)
+		}
+		if stats.Recovery != MaxStat {
+			t.Fatalf("gifted recovery went past the max to %.2f", stats.Recovery)
+		}
+	}
+}
+
+func TestBackfillGenomes(t *testing.T) {
+	db := newTestDB(t)
+	bred := Snail{Name: "bred", OwnerID: "owner", Genome: "ssttrrccpp"}
+	old := Snail{Name: "old", OwnerID: "owner"}
+	for _, snail := range []*Snail{&bred, &old} {
+		if err := db.Create(snail).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := BackfillGenomes(db); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&bred, bred.ID)
+	db.First(&old, old.ID)
+	if bred.Genome != "ssttrrccpp" {
+		t.Errorf("existing genome was replaced with %q", bred.Genome)
+	}
+	if !old.Genome.Valid() {
+		t.Errorf("old snail was given genome %q", old.Genome)
+	}
+
+	// Running it again leaves the backfilled genome alone
+	genome := old.Genome
+	if err := BackfillGenomes(db); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&old, old.ID)
+	if old.Genome != genome {
+		t.Errorf("backfilled genome changed from %q to %q", genome, old.Genome)
+	}
+}
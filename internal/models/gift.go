@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Gift Constants, the caps apply to both what a user gives and what they
+	// receive so alt accounts can't funnel everything into one account
+	DailyGiftMoneyCap = 500
+	DailyGiftSnailCap = 3
+	GiftCapWindow     = 24 * time.Hour
+)
+
+var (
+	ErrNothingToGift  = fmt.Errorf("nothing to gift")
+	ErrGiftToSelf     = fmt.Errorf("can't gift to yourself")
+	ErrGiftCapReached = fmt.Errorf("daily gift cap reached")
+)
+
+// Gift is a record of money and/or a snail given from one user to another,
+// they are kept so the daily caps can be checked.
+type Gift struct {
+	gorm.Model
+
+	FromID    string `gorm:"index"`
+	ToID      string `gorm:"index"`
+	Money     int64
+	SnailID   uint
+	SnailName string
+}
+
+// How much money and how many snails the user has given, or received, within
+// the cap window
+func giftedSince(tx *gorm.DB, column string, discordID string) (int64, int64, error) {
+	totals := struct {
+		Money  int64
+		Snails int64
+	}{}
+	result := tx.Model(&Gift{}).
+		Select("COALESCE(SUM(money), 0) AS money, COUNT(CASE WHEN snail_id > 0 THEN 1 END) AS snails").
+		Where(column+" = ? AND created_at > ?", discordID, time.Now().Add(-GiftCapWindow)).
+		Scan(&totals)
+	return totals.Money, totals.Snails, result.Error
+}
+
+// GiftAllowance is how much money and how many snails the user can still give
+// away today.
+func GiftAllowance(db *gorm.DB, discordID string) (int64, int64, error) {
+	money, snails, err := giftedSince(db, "from_id", discordID)
+	return DailyGiftMoneyCap - money, DailyGiftSnailCap - snails, err
+}
+
+// GiveGift moves the money and/or snail from one user to the other. The snail
+// can't be in escrow or be the giver's last snail, checking it isn't racing is
+// left to the caller as it needs the race state.
+func GiveGift(db *gorm.DB, from User, to User, money int64, snail *Snail) (*Gift, error) {
+	log.Debugf("GiveGift(from: %s, to: %s, money: %d)", from.DiscordID, to.DiscordID, money)
+
+	if from.DiscordID == to.DiscordID {
+		return nil, ErrGiftToSelf
+	}
+	if money < 0 {
+		return nil, ErrInvalidAmount
+	}
+	if money == 0 && snail == nil {
+		return nil, ErrNothingToGift
+	}
+
+	gift := &Gift{FromID: from.DiscordID, ToID: to.DiscordID, Money: money}
+	if snail != nil {
+		gift.SnailID, gift.SnailName = snail.ID, snail.Name
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := GetUserByDiscordID(tx, to.DiscordID); err != nil {
+			return err
+		}
+
+		// The gift is recorded first so it counts towards the caps, if it
+		// takes either user over then the whole gift is rolled back
+		if err := tx.Create(gift).Error; err != nil {
+			return err
+		}
+		givenMoney, givenSnails, err := giftedSince(tx, "from_id", from.DiscordID)
+		if err != nil {
+			return err
+		}
+		receivedMoney, receivedSnails, err := giftedSince(tx, "to_id", to.DiscordID)
+		if err != nil {
+			return err
+		}
+		if givenMoney > DailyGiftMoneyCap || givenSnails > DailyGiftSnailCap ||
+			receivedMoney > DailyGiftMoneyCap || receivedSnails > DailyGiftSnailCap {
+			return ErrGiftCapReached
+		}
+
+		if money > 0 {
+			if _, err := Debit(tx, from.DiscordID, money, LedgerGift, fmt.Sprintf("Gift to %s", Mention(to.DiscordID))); err != nil {
+				return err
+			}
+			if _, err := Credit(tx, to.DiscordID, money, LedgerGift, fmt.Sprintf("Gift from %s", Mention(from.DiscordID))); err != nil {
+				return err
+			}
+		}
+
+		if snail != nil {
+			keeps, err := keepsASnail(tx, from.DiscordID, 1, 0)
+			if err != nil {
+				return err
+			}
+			if !keeps {
+				return ErrLastSnail
+			}
+
+			result := tx.Model(&Snail{}).
+				Where("id = ? AND owner_id = ? AND escrow = ''", snail.ID, from.DiscordID).
+				Updates(map[string]interface{}{"owner_id": to.DiscordID, "active": false})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return ErrSnailInEscrow
+			}
+			if err := ensureActiveSnail(tx, from.DiscordID); err != nil {
+				return err
+			}
+			return ensureActiveSnail(tx, to.DiscordID)
+		}
+		return nil
+	})
+	return gift, err
+}
@@ -0,0 +1,53 @@
+package models
+
+import "testing"
+
+func TestGiveGiftMovesMoneyAndSnail(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "from", 100)
+	newTestUser(t, db, "to", 1)
+	from, _ := GetUserByDiscordID(db, "from")
+	to, _ := GetUserByDiscordID(db, "to")
+	snails := []Snail{newTestOwnedSnail(t, db, "from"), newTestOwnedSnail(t, db, "from")}
+
+	if _, err := GiveGift(db, *from, *to, 50, &snails[0]); err != nil {
+		t.Fatal(err)
+	}
+	from, _ = GetUserByDiscordID(db, "from")
+	to, _ = GetUserByDiscordID(db, "to")
+	if from.Money != 60 || to.Money != 61 {
+		t.Errorf("balances after gift are %d and %d", from.Money, to.Money)
+	}
+	gifted := Snail{}
+	db.First(&gifted, snails[0].ID)
+	if gifted.OwnerID != "to" {
+		t.Errorf("gifted snail is owned by %s", gifted.OwnerID)
+	}
+
+	// The giver has to keep a snail, and a failed gift moves no money
+	if _, err := GiveGift(db, *from, *to, 10, &snails[1]); err != ErrLastSnail {
+		t.Errorf("gave away the last snail, got %v", err)
+	}
+	from, _ = GetUserByDiscordID(db, "from")
+	if from.Money != 60 {
+		t.Errorf("failed gift changed the balance to %d", from.Money)
+	}
+}
+
+func TestGiveGiftDailyCap(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "from", DailyGiftMoneyCap*2)
+	newTestUser(t, db, "to", 1)
+	from, _ := GetUserByDiscordID(db, "from")
+	to, _ := GetUserByDiscordID(db, "to")
+
+	if _, err := GiveGift(db, *from, *to, DailyGiftMoneyCap, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GiveGift(db, *from, *to, 1, nil); err != ErrGiftCapReached {
+		t.Errorf("gave more than the daily cap, got %v", err)
+	}
+	if money, _, _ := GiftAllowance(db, "from"); money != 0 {
+		t.Errorf("allowance left after hitting the cap is %d", money)
+	}
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Economy string
+
+const (
+	EconomyGlobal Economy = "global" // Wallets and snails are shared with every guild
+	EconomyGuild  Economy = "guild"  // Wallets and snails only exist in the guild
+
+	// Accounts in a guild economy are the guild and user ids joined by the
+	// separator, global accounts are just the user id
+	accountSeparator = "/"
+)
+
+var ErrInvalidEconomy = fmt.Errorf("invalid economy")
+
+// GuildConfig is the settings a guild has picked, guilds without one use the
+// defaults.
+type GuildConfig struct {
+	gorm.Model
+
+	GuildID string  `gorm:"uniqueIndex"`
+	Economy Economy `gorm:"default:global"`
+}
+
+func ParseEconomy(value string) (Economy, error) {
+	switch Economy(value) {
+	case EconomyGlobal, EconomyGuild:
+		return Economy(value), nil
+	}
+	return EconomyGlobal, ErrInvalidEconomy
+}
+
+// A short human description of the economy
+func (e Economy) Title() string {
+	if e == EconomyGuild {
+		return "Server"
+	}
+	return "Global"
+}
+
+// GetGuildEconomy gets the economy the guild plays in, guilds that haven't
+// picked one play in the global economy.
+func GetGuildEconomy(db *gorm.DB, guildID string) (Economy, error) {
+	if guildID == "" {
+		return EconomyGlobal, nil
+	}
+
+	config := &GuildConfig{}
+	result := db.Where("guild_id = ?", guildID).First(config)
+	if result.Error == gorm.ErrRecordNotFound {
+		return EconomyGlobal, nil
+	}
+	if result.Error != nil {
+		return EconomyGlobal, result.Error
+	}
+	return config.Economy, nil
+}
+
+// SetGuildEconomy switches the economy the guild plays in and records who
+// switched it. Nothing is moved between economies, so switching back picks up
+// where the guild left off.
+func SetGuildEconomy(db *gorm.DB, adminID string, guildID string, economy Economy) error {
+	log.Debugf("SetGuildEconomy(guild: %s, economy: %s)", guildID, economy)
+
+	if _, err := ParseEconomy(string(economy)); err != nil || guildID == "" {
+		return ErrInvalidEconomy
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "guild_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"economy", "updated_at"}),
+		}).Create(&GuildConfig{GuildID: guildID, Economy: economy}).Error
+		if err != nil {
+			return err
+		}
+		return RecordAdminAction(tx, adminID, "economy", guildID, string(economy))
+	})
+}
+
+// AccountID gets the id the user's wallet and snails are kept under in the
+// guild. This is the user's discord id, unless the guild has its own economy.
+func AccountID(db *gorm.DB, guildID string, discordID string) (string, error) {
+	economy, err := GetGuildEconomy(db, guildID)
+	if err != nil {
+		return "", err
+	}
+	if economy == EconomyGuild {
+		return guildID + accountSeparator + discordID, nil
+	}
+	return discordID, nil
+}
+
+// The discord id of the user that owns the account
+func MemberID(accountID string) string {
+	return accountID[strings.LastIndex(accountID, accountSeparator)+1:]
+}
+
+// Mention the user that owns the account
+func Mention(accountID string) string {
+	return fmt.Sprintf("<@%s>", MemberID(accountID))
+}
+
+// The guild the account belongs to, or empty for global accounts
+func accountGuild(accountID string) string {
+	if index := strings.LastIndex(accountID, accountSeparator); index >= 0 {
+		return accountID[:index]
+	}
+	return ""
+}
+
+// Whether the accounts are in the same economy, money and snails can only
+// move between accounts in the same economy.
+func SameEconomy(a string, b string) bool {
+	return accountGuild(a) == accountGuild(b)
+}
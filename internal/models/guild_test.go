@@ -0,0 +1,59 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGuildEconomyAccounts(t *testing.T) {
+	db := newTestDB(t)
+
+	if id, _ := AccountID(db, "guild", "user"); id != "user" {
+		t.Errorf("guilds should default to the global economy, got account %s", id)
+	}
+
+	if err := SetGuildEconomy(db, "owner", "guild", EconomyGuild); err != nil {
+		t.Fatal(err)
+	}
+	local, _ := AccountID(db, "guild", "user")
+	if local != "guild/user" || MemberID(local) != "user" || Mention(local) != "<@user>" {
+		t.Errorf("guild economy account is %s", local)
+	}
+	if SameEconomy(local, "user") || !SameEconomy(local, "guild/other") {
+		t.Error("guild accounts should only share an economy with the guild")
+	}
+
+	// Switching back is an update, not a second config
+	if err := SetGuildEconomy(db, "owner", "guild", EconomyGlobal); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := AccountID(db, "guild", "user"); id != "user" {
+		t.Errorf("switching back should use the global account, got %s", id)
+	}
+	var actions int64
+	db.Model(&AdminAction{}).Where("action = ?", "economy").Count(&actions)
+	if actions != 2 {
+		t.Errorf("expected both economy changes to be audited, got %d", actions)
+	}
+}
+
+func TestStateRacesScopedToChannel(t *testing.T) {
+	state := NewState(newTestDB(t))
+
+	race, err := state.NewRace(nil, "guild", "channel", &discordgo.User{Username: "host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer race.EndRace()
+
+	if _, ok := state.GetRace("other", "channel", race.Id); ok {
+		t.Error("found the race from another guild")
+	}
+	if _, ok := state.GetRace("guild", "other", race.Id); ok {
+		t.Error("found the race from another channel")
+	}
+	if !state.GuildRacing("guild") || state.GuildRacing("other") {
+		t.Error("only the hosting guild should be racing")
+	}
+}
@@ -0,0 +1,381 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type HypeMode string
+type HypeStatus string
+
+const (
+	HypeModeRace  HypeMode = "race"  // Cheer on a snail in the next race
+	HypeModeCrowd HypeMode = "crowd" // Rally a crowd behind a snail
+
+	HypeStatusOpen   HypeStatus = "open"
+	HypeStatusPassed HypeStatus = "passed"
+	HypeStatusFailed HypeStatus = "failed"
+
+	// Hype Constants
+	HypeCrowdSize = 5               // How many users a crowd hype needs
+	HypeWindow    = 5 * time.Minute // How long a crowd has to gather
+
+	// Action Ids
+	HypeActionCheer = "hype_cheer"
+)
+
+var (
+	ErrHypeNotFound = fmt.Errorf("hype not found")
+	ErrHypeClosed   = fmt.Errorf("hype is over")
+	ErrOwnHype      = fmt.Errorf("can't hype your own snail")
+	ErrAlreadyHyped = fmt.Errorf("already hyped")
+	ErrHypeOpen     = fmt.Errorf("snail already has a crowd gathering")
+	ErrMoodLocked   = fmt.Errorf("snail's mood is locked")
+)
+
+// Hype is users cheering on someone else's snail, focusing it for its next
+// MoodLockRaces races. A race hype needs the snail to win the race it has
+// entered, a crowd hype needs HypeCrowdSize users to cheer within the window.
+// Only crowd hypes are posted with a button.
+type Hype struct {
+	gorm.Model
+
+	HypeID    string `gorm:"uniqueIndex"`
+	Mode      HypeMode
+	RaceID    string `gorm:"index"` // Only for race hypes
+	ChannelID string
+	MessageID string
+
+	OwnerID string `gorm:"index"`
+	SnailID uint   `gorm:"index"`
+	Snail   Snail
+	Cheers  int
+
+	Status HypeStatus `gorm:"index"`
+	EndsAt time.Time
+}
+
+// HypeCheer is a user cheering on a hype, users can only cheer once per hype
+type HypeCheer struct {
+	gorm.Model
+
+	HypeID string `gorm:"uniqueIndex:idx_hype_cheer"`
+	UserID string `gorm:"uniqueIndex:idx_hype_cheer"`
+}
+
+// CreateCrowdHype starts gathering a crowd behind the owner's snail, only one
+// crowd can gather behind a snail at a time.
+func CreateCrowdHype(db *gorm.DB, owner User, snail Snail) (*Hype, error) {
+	log.Debugf("CreateCrowdHype(owner: %s, snail: %d)", owner.DiscordID, snail.ID)
+
+	hype := &Hype{
+		HypeID:  uuid.New().String()[24:],
+		Mode:    HypeModeCrowd,
+		OwnerID: owner.DiscordID,
+		SnailID: snail.ID,
+		Status:  HypeStatusOpen,
+		EndsAt:  time.Now().Add(HypeWindow),
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND owner_id = ?", snail.ID, owner.DiscordID).First(&Snail{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var open int64
+		if err := tx.Model(&Hype{}).Where("snail_id = ? AND mode = ? AND status = ?", snail.ID, HypeModeCrowd, HypeStatusOpen).Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrHypeOpen
+		}
+		return tx.Create(hype).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hype.Snail = snail
+	return hype, nil
+}
+
+func GetHype(db *gorm.DB, hypeId string) (*Hype, error) {
+	log.Debugf("GetHype(hype: %s)", hypeId)
+
+	hype := &Hype{}
+	result := db.Where("hype_id = ?", hypeId).
+		Preload("Snail", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(hype)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrHypeNotFound
+	}
+	return hype, result.Error
+}
+
+// Record the user's cheer, ErrAlreadyHyped is returned if they have already
+// cheered on the hype.
+func (h *Hype) cheer(tx *gorm.DB, userID string) error {
+	if MemberID(userID) == MemberID(h.OwnerID) {
+		return ErrOwnHype
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&HypeCheer{HypeID: h.HypeID, UserID: userID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlreadyHyped
+	}
+
+	return tx.Model(&Hype{}).Where("id = ?", h.ID).Update("cheers", gorm.Expr("cheers + 1")).Error
+}
+
+// Mark the open hype as finished, ErrHypeClosed is returned if it was already
+// finished by someone else.
+func (h *Hype) close(tx *gorm.DB, status HypeStatus) error {
+	result := tx.Model(&Hype{}).
+		Where("id = ? AND status = ?", h.ID, HypeStatusOpen).
+		Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrHypeClosed
+	}
+	h.Status = status
+	return nil
+}
+
+// CheerCrowdHype adds the user to the crowd, once the crowd is big enough
+// the snail is focused for its next MoodLockRaces races.
+func CheerCrowdHype(db *gorm.DB, hype *Hype, userID string) error {
+	log.Debugf("CheerCrowdHype(hype: %s, user: %s)", hype.HypeID, userID)
+
+	if hype.Status != HypeStatusOpen || time.Now().After(hype.EndsAt) {
+		return ErrHypeClosed
+	}
+	if !SameEconomy(hype.OwnerID, userID) {
+		return ErrHypeNotFound
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := hype.cheer(tx, userID); err != nil {
+			return err
+		}
+
+		current := &Hype{}
+		if err := tx.Select("id", "status", "cheers").First(current, hype.ID).Error; err != nil {
+			return err
+		}
+		if current.Status != HypeStatusOpen {
+			return ErrHypeClosed
+		}
+		hype.Cheers = current.Cheers
+		if hype.Cheers < HypeCrowdSize {
+			return nil
+		}
+
+		if err := hype.close(tx, HypeStatusPassed); err != nil {
+			return err
+		}
+		return LockMood(tx, hype.SnailID, MoodFocused, MoodLockRaces)
+	})
+}
+
+// ExpireHype lets the crowd go home if it didn't get big enough in time
+func ExpireHype(db *gorm.DB, hype *Hype) error {
+	log.Debugf("ExpireHype(hype: %s)", hype.HypeID)
+	return hype.close(db, HypeStatusFailed)
+}
+
+// HypeRaceSnail cheers on a snail that has entered the race, if it wins the
+// race it is focused for its next MoodLockRaces races. Every user can cheer on
+// each snail once per race, but a snail that already has a locked mood can't
+// be hyped.
+func HypeRaceSnail(db *gorm.DB, raceId string, snail Snail, userID string) error {
+	log.Debugf("HypeRaceSnail(race: %s, snail: %d, user: %s)", raceId, snail.ID, userID)
+
+	hype := &Hype{
+		HypeID:  fmt.Sprintf("%s-%d", raceId, snail.ID),
+		Mode:    HypeModeRace,
+		RaceID:  raceId,
+		OwnerID: snail.OwnerID,
+		SnailID: snail.ID,
+		Status:  HypeStatusOpen,
+	}
+	if MemberID(userID) == MemberID(hype.OwnerID) {
+		return ErrOwnHype
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		current := &Snail{}
+		if err := tx.Select("id", "mood_races").First(current, snail.ID).Error; err != nil {
+			return err
+		}
+		if current.MoodRaces > 0 {
+			return ErrMoodLocked
+		}
+
+		// Everyone after the first cheer is joining in
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(hype).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hype_id = ?", hype.HypeID).First(hype).Error; err != nil {
+			return err
+		}
+		if hype.Status != HypeStatusOpen {
+			return ErrHypeClosed
+		}
+		return hype.cheer(tx, userID)
+	})
+}
+
+// Settle the race hypes once the race is over, the hyped winners are focused
+// for their next MoodLockRaces races and every other hype fails.
+func settleRaceHypes(tx *gorm.DB, raceId string, winners []uint) error {
+	hyped := []Hype{}
+	err := tx.Where("race_id = ? AND mode = ? AND status = ? AND snail_id IN ?", raceId, HypeModeRace, HypeStatusOpen, winners).
+		Find(&hyped).Error
+	if err != nil {
+		return err
+	}
+
+	for _, hype := range hyped {
+		if err := hype.close(tx, HypeStatusPassed); err != nil {
+			return err
+		}
+		if err := LockMood(tx, hype.SnailID, MoodFocused, MoodLockRaces); err != nil {
+			return err
+		}
+	}
+	return failRaceHypes(tx, raceId)
+}
+
+// Fail the race hypes that are still open, for losers and cancelled races
+func failRaceHypes(tx *gorm.DB, raceId string) error {
+	return tx.Model(&Hype{}).
+		Where("race_id = ? AND mode = ? AND status = ?", raceId, HypeModeRace, HypeStatusOpen).
+		Update("status", HypeStatusFailed).Error
+}
+
+// PostHype sends the crowd hype to the channel with a button to cheer, and
+// then watches for the window to close.
+func PostHype(s *discordgo.Session, db *gorm.DB, hype *Hype, channelId string) error {
+	message, err := s.ChannelMessageSendComplex(channelId, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{hype.embed()},
+		Components: hype.components(),
+	})
+	if err != nil {
+		return err
+	}
+
+	hype.ChannelID, hype.MessageID = channelId, message.ID
+	if err := db.Model(hype).Updates(map[string]interface{}{"channel_id": channelId, "message_id": message.ID}).Error; err != nil {
+		return err
+	}
+
+	goBackground(func(ctx context.Context) { WatchHype(ctx, s, db, hype.HypeID) })
+	return nil
+}
+
+// WatchHype waits for the crowd hype's window to close and fails it if the
+// crowd didn't get big enough.
+func WatchHype(ctx context.Context, s *discordgo.Session, db *gorm.DB, hypeId string) {
+	hype, err := GetHype(db, hypeId)
+	if err != nil {
+		log.WithField("hype", hypeId).WithError(err).Warn("Failed to get hype to watch")
+		return
+	}
+
+	if !sleepBackground(ctx, time.Until(hype.EndsAt)) {
+		return
+	}
+
+	switch err := ExpireHype(db, hype); err {
+	case nil:
+		log.WithField("hype", hypeId).Info("Hype failed")
+		hype.Render(s)
+	case ErrHypeClosed:
+	default:
+		log.WithField("hype", hypeId).WithError(err).Error("Failed to expire hype")
+	}
+}
+
+// ResumeHypes watches the crowd hypes that were still gathering when the bot
+// stopped, any that ran out of time in the meantime fail straight away.
+func ResumeHypes(s *discordgo.Session, db *gorm.DB) {
+	hypes := []Hype{}
+	if err := db.Where("mode = ? AND status = ?", HypeModeCrowd, HypeStatusOpen).Find(&hypes).Error; err != nil {
+		log.WithError(err).Error("Failed to find open hypes")
+		return
+	}
+
+	for _, hype := range hypes {
+		hypeId := hype.HypeID
+		goBackground(func(ctx context.Context) { WatchHype(ctx, s, db, hypeId) })
+	}
+}
+
+func (h *Hype) Render(s *discordgo.Session) {
+	if h.MessageID == "" {
+		return
+	}
+
+	edit := discordgo.NewMessageEdit(h.ChannelID, h.MessageID)
+	edit.Embeds = []*discordgo.MessageEmbed{h.embed()}
+	edit.Components = h.components()
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		log.WithField("hype", h.HypeID).WithError(err).Warn("Failed to render hype")
+	}
+}
+
+func (h *Hype) embed() *discordgo.MessageEmbed {
+	body := fmt.Sprintf("%s wants a crowd behind 🐌 **%s** (lvl. %d)!\n\n", Mention(h.OwnerID), h.Snail.Name, h.Snail.Level)
+	body += fmt.Sprintf("📣 **%d/%d** cheers\n\n", h.Cheers, HypeCrowdSize)
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Hype `%s`", h.HypeID),
+		Color: 0xf1c40f,
+	}
+
+	switch h.Status {
+	case HypeStatusOpen:
+		body += fmt.Sprintf("If %d people cheer <t:%d:R> then %s will be %s for the next %d races.", HypeCrowdSize, h.EndsAt.Unix(), h.Snail.Name, MoodFocused.Title(), MoodLockRaces)
+	case HypeStatusPassed:
+		body += fmt.Sprintf("The crowd has spoken, %s is %s for the next %d races!", h.Snail.Name, MoodFocused.Title(), MoodLockRaces)
+		embed.Color = 0x2ecc71
+	case HypeStatusFailed:
+		body += "Not enough people turned up in time, maybe next time."
+		embed.Color = 0xe74c3c
+	}
+
+	embed.Description = body
+	return embed
+}
+
+func (h *Hype) components() []discordgo.MessageComponent {
+	if h.Status != HypeStatusOpen {
+		return []discordgo.MessageComponent{}
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "📣 Hype!",
+					Style:    discordgo.PrimaryButton,
+					CustomID: fmt.Sprintf("%s:%s", HypeActionCheer, h.HypeID),
+				},
+			},
+		},
+	}
+}
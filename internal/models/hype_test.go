@@ -0,0 +1,126 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCrowdHypeFocusesSnail(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	owner, _ := GetUserByDiscordID(db, "owner")
+	snail := newTestOwnedSnail(t, db, "owner")
+
+	hype, err := CreateCrowdHype(db, *owner, snail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateCrowdHype(db, *owner, snail); err != ErrHypeOpen {
+		t.Errorf("started a second crowd, got %v", err)
+	}
+
+	// The owner can't cheer and nobody can cheer twice
+	if err := CheerCrowdHype(db, hype, "owner"); err != ErrOwnHype {
+		t.Errorf("owner cheered their own hype, got %v", err)
+	}
+	if err := CheerCrowdHype(db, hype, "fan0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheerCrowdHype(db, hype, "fan0"); err != ErrAlreadyHyped {
+		t.Errorf("cheered twice, got %v", err)
+	}
+
+	for n := 1; n < HypeCrowdSize; n++ {
+		if err := CheerCrowdHype(db, hype, fmt.Sprintf("fan%d", n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hype.Status != HypeStatusPassed || hype.Cheers != HypeCrowdSize {
+		t.Errorf("hype is %s with %d cheers", hype.Status, hype.Cheers)
+	}
+	hyped := Snail{}
+	db.First(&hyped, snail.ID)
+	if hyped.MoodLock != MoodFocused || hyped.MoodRaces != MoodLockRaces {
+		t.Errorf("hyped snail has mood %d for %d races", hyped.MoodLock, hyped.MoodRaces)
+	}
+	if err := CheerCrowdHype(db, hype, "late"); err != ErrHypeClosed {
+		t.Errorf("cheered a finished hype, got %v", err)
+	}
+}
+
+func TestRaceHype(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	winner := newTestOwnedSnail(t, db, "owner")
+	loser := newTestOwnedSnail(t, db, "owner")
+
+	if err := HypeRaceSnail(db, "race", winner, "owner"); err != ErrOwnHype {
+		t.Errorf("owner hyped their own snail, got %v", err)
+	}
+	for _, snail := range []Snail{winner, loser} {
+		if err := HypeRaceSnail(db, "race", snail, "fan"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := HypeRaceSnail(db, "race", winner, "fan"); err != ErrAlreadyHyped {
+		t.Errorf("hyped twice in a race, got %v", err)
+	}
+	if err := HypeRaceSnail(db, "race", winner, "other"); err != nil {
+		t.Errorf("second fan couldn't join in, got %v", err)
+	}
+
+	// Nothing changes until the race is over
+	hyped := Snail{}
+	db.First(&hyped, winner.ID)
+	if hyped.MoodRaces != 0 {
+		t.Errorf("hyped snail has mood %d for %d races before racing", hyped.MoodLock, hyped.MoodRaces)
+	}
+
+	if err := settleRaceHypes(db, "race", []uint{winner.ID}); err != nil {
+		t.Fatal(err)
+	}
+	for snail, want := range map[uint]uint64{winner.ID: MoodLockRaces, loser.ID: 0} {
+		hyped := Snail{}
+		db.First(&hyped, snail)
+		if hyped.MoodRaces != want || (want > 0 && hyped.MoodLock != MoodFocused) {
+			t.Errorf("snail %d has mood %d for %d races, want %d races", snail, hyped.MoodLock, hyped.MoodRaces, want)
+		}
+	}
+	for snail, want := range map[uint]HypeStatus{winner.ID: HypeStatusPassed, loser.ID: HypeStatusFailed} {
+		hype, _ := GetHype(db, fmt.Sprintf("race-%d", snail))
+		if hype.Status != want || hype.RaceID != "race" {
+			t.Errorf("hype for snail %d in race %q is %s, want %s", snail, hype.RaceID, hype.Status, want)
+		}
+	}
+	if err := HypeRaceSnail(db, "race", loser, "late"); err != ErrHypeClosed {
+		t.Errorf("hyped a snail in a finished race, got %v", err)
+	}
+
+	// A locked mood can't be replaced by a race hype
+	if err := HypeRaceSnail(db, "next", winner, "fan"); err != ErrMoodLocked {
+		t.Errorf("hyped a snail with a locked mood, got %v", err)
+	}
+}
+
+func TestCancelledRaceFailsHypes(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	race := newTestRace(t, db)
+	snail := newTestOwnedSnail(t, db, "owner")
+	if err := race.AddSnail(&snail); err != nil {
+		t.Fatal(err)
+	}
+	if err := HypeRaceSnail(db, race.Id, snail, "fan"); err != nil {
+		t.Fatal(err)
+	}
+
+	record := &RaceRecord{}
+	db.First(record, race.recordID)
+	if err := CancelRaceRecord(db, record); err != nil {
+		t.Fatal(err)
+	}
+	hype, _ := GetHype(db, fmt.Sprintf("%s-%d", race.Id, snail.ID))
+	if hype.Status != HypeStatusFailed {
+		t.Errorf("hype in a cancelled race is %s", hype.Status)
+	}
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type LeaderboardKind string
+
+const (
+	LeaderboardLevel  LeaderboardKind = "level"
+	LeaderboardWins   LeaderboardKind = "wins"
+	LeaderboardXP     LeaderboardKind = "xp"
+	LeaderboardSnails LeaderboardKind = "snails"
+
+	LeaderboardSize = 10
+)
+
+var LeaderboardKinds = []LeaderboardKind{LeaderboardLevel, LeaderboardWins, LeaderboardXP, LeaderboardSnails}
+
+// UserGuild records that a user has played in a guild, so the leaderboards
+// can be scoped to the guild.
+type UserGuild struct {
+	gorm.Model
+
+	UserDiscordID string `gorm:"uniqueIndex:idx_user_guild"`
+	GuildID       string `gorm:"uniqueIndex:idx_user_guild;index"`
+}
+
+// LeaderboardEntry is a single row of a leaderboard, the snail board fills in
+// the snail's name and the owner as the discord id.
+type LeaderboardEntry struct {
+	Rank      int64
+	DiscordID string
+	SnailName string
+	Level     uint64
+	Wins      uint64
+	Races     uint64
+	Score     int64
+}
+
+// TrackGuild records that the user has played in the guild
+func TrackGuild(db *gorm.DB, discordID string, guildID string) error {
+	if guildID == "" {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserGuild{UserDiscordID: discordID, GuildID: guildID}).Error
+}
+
+// A short human description of the leaderboard
+func (kind LeaderboardKind) Title() string {
+	switch kind {
+	case LeaderboardLevel:
+		return "Level"
+	case LeaderboardWins:
+		return "Wins"
+	case LeaderboardXP:
+		return "XP"
+	case LeaderboardSnails:
+		return "Snails"
+	}
+	return string(kind)
+}
+
+// The SQL the board is ranked by. XP is stored per level, so the total is
+// worked out from the XP it took to get to the level.
+func (kind LeaderboardKind) score() string {
+	switch kind {
+	case LeaderboardWins, LeaderboardSnails:
+		return "wins"
+	}
+	return "(level * (level - 1) * 50 + xp)"
+}
+
+// The column the board's rows are owned by
+func (kind LeaderboardKind) owner() string {
+	if kind == LeaderboardSnails {
+		return "owner_id"
+	}
+	return "discord_id"
+}
+
+// Every row the board could rank
+func (kind LeaderboardKind) rows(db *gorm.DB) *gorm.DB {
+	if kind == LeaderboardSnails {
+		return db.Model(&Snail{}).Select("owner_id AS discord_id, name AS snail_name, level, wins, races, wins AS score")
+	}
+	return db.Model(&User{}).Select(fmt.Sprintf("discord_id, level, wins, races, %s AS score", kind.score()))
+}
+
+// The rows the board ranks, only the ones that have played in the guild if
+// there is one. The global board leaves out accounts in guild economies.
+func (kind LeaderboardKind) scope(db *gorm.DB, guildID string) *gorm.DB {
+	if guildID == "" {
+		return kind.rows(db).Where(kind.owner()+" NOT LIKE ?", "%"+accountSeparator+"%")
+	}
+
+	guilds := db.Model(&UserGuild{}).Select("user_discord_id").Where("guild_id = ?", guildID)
+	return kind.rows(db).Where(kind.owner()+" IN (?)", guilds)
+}
+
+// GetLeaderboard gets the top of the board, scoped to the guild unless the
+// guild is empty.
+func GetLeaderboard(db *gorm.DB, kind LeaderboardKind, guildID string) ([]LeaderboardEntry, error) {
+	log.Debugf("GetLeaderboard(kind: %s, guild: %s)", kind, guildID)
+
+	entries := []LeaderboardEntry{}
+	result := kind.scope(db, guildID).Order("score desc, races asc, id asc").Limit(LeaderboardSize).Scan(&entries)
+	for index := range entries {
+		entries[index].Rank = int64(index + 1)
+	}
+	return entries, result.Error
+}
+
+// GetLeaderboardRank gets the user's place on the board, for the snail board
+// this is their best snail.
+func GetLeaderboardRank(db *gorm.DB, kind LeaderboardKind, guildID string, discordID string) (*LeaderboardEntry, error) {
+	log.Debugf("GetLeaderboardRank(kind: %s, guild: %s, id: %s)", kind, guildID, discordID)
+
+	// The score is looked up without the guild, so users that haven't played
+	// in the guild can still see where they would place in it
+	entry := &LeaderboardEntry{}
+	result := kind.rows(db).Where(kind.owner()+" = ?", discordID).Order("score desc").Limit(1).Scan(entry)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var above int64
+	query := kind.scope(db, guildID).Where(kind.score()+" > ?", entry.Score)
+	if err := db.Table("(?) AS ranked", query).Count(&above).Error; err != nil {
+		return nil, err
+	}
+	entry.Rank = above + 1
+	return entry, nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import "testing"
+
+func TestLeaderboardScopedToGuild(t *testing.T) {
+	db := newTestDB(t)
+	for index, id := range []string{"first", "second", "elsewhere"} {
+		db.Create(&User{DiscordID: id, Level: uint64(5 - index), Wins: uint64(index)})
+	}
+	TrackGuild(db, "first", "guild")
+	TrackGuild(db, "second", "guild")
+	TrackGuild(db, "second", "guild")
+	TrackGuild(db, "elsewhere", "other")
+
+	board, err := GetLeaderboard(db, LeaderboardLevel, "guild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(board) != 2 || board[0].DiscordID != "first" || board[1].DiscordID != "second" {
+		t.Errorf("guild level board is %v", board)
+	}
+
+	board, _ = GetLeaderboard(db, LeaderboardWins, "")
+	if len(board) != 3 || board[0].DiscordID != "elsewhere" {
+		t.Errorf("global wins board is %v", board)
+	}
+
+	rank, err := GetLeaderboardRank(db, LeaderboardWins, "guild", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rank.Rank != 2 {
+		t.Errorf("first is rank %d on the guild wins board, expected 2", rank.Rank)
+	}
+
+	db.Create(&Snail{Name: "fast", OwnerID: "second", Wins: 7, Races: 10})
+	db.Create(&Snail{Name: "faster", OwnerID: "elsewhere", Wins: 9, Races: 10})
+	board, _ = GetLeaderboard(db, LeaderboardSnails, "guild")
+	if len(board) != 1 || board[0].SnailName != "fast" || board[0].DiscordID != "second" {
+		t.Errorf("guild snail board is %v", board)
+	}
+	rank, _ = GetLeaderboardRank(db, LeaderboardSnails, "", "second")
+	if rank == nil || rank.Rank != 2 {
+		t.Errorf("second's best snail has rank %v globally, expected 2", rank)
+	}
+}
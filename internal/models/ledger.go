@@ -0,0 +1,141 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type LedgerKind string
+
+const (
+	LedgerBetPlaced LedgerKind = "bet_placed"
+	LedgerBetWon    LedgerKind = "bet_won"
+	LedgerBetRefund LedgerKind = "bet_refund"
+	LedgerRacePrize LedgerKind = "race_prize"
+	LedgerGift      LedgerKind = "gift"
+	LedgerPurchase  LedgerKind = "purchase"
+	LedgerBreeding  LedgerKind = "breeding"
+	LedgerEscrow    LedgerKind = "escrow"
+	LedgerTrade     LedgerKind = "trade"
+	LedgerAuction   LedgerKind = "auction"
+	LedgerRaffle    LedgerKind = "raffle"
+	LedgerSale      LedgerKind = "sale"
+	LedgerAdmin     LedgerKind = "admin"
+)
+
+var (
+	ErrInsufficientFunds = fmt.Errorf("insufficient funds")
+	ErrInvalidAmount     = fmt.Errorf("invalid amount")
+)
+
+// LedgerEntry is a single signed change to a user's balance. The user's money
+// is only ever changed by applying an entry, so the ledger is the full history
+// of how the balance got to where it is.
+type LedgerEntry struct {
+	gorm.Model
+
+	UserDiscordID string     `gorm:"index"`
+	Amount        int64      // Signed change in money
+	Balance       int64      // The balance after the entry was applied
+	Kind          LedgerKind `gorm:"index"`
+	Memo          string
+}
+
+// ApplyLedgerEntry atomically changes the user's balance by the amount and
+// records the entry. If the entry would take the balance below zero then
+// nothing is changed and ErrInsufficientFunds is returned. This can be called
+// inside another transaction to group entries together.
+func ApplyLedgerEntry(db *gorm.DB, discordID string, amount int64, kind LedgerKind, memo string) (*LedgerEntry, error) {
+	log.Debugf("ApplyLedgerEntry(id: %s, amount: %d, kind: %s)", discordID, amount, kind)
+
+	entry := &LedgerEntry{
+		UserDiscordID: discordID,
+		Amount:        amount,
+		Kind:          kind,
+		Memo:          memo,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// The balance check and update happen in a single statement, so
+		// concurrent entries can't both spend the same money.
+		result := tx.Model(&User{}).
+			Where("discord_id = ? AND money + ? >= 0", discordID, amount).
+			UpdateColumn("money", gorm.Expr("money + ?", amount))
+		if result.Error != nil {
+			return result.Error
+		}
+
+		user := &User{}
+		if err := tx.Where("discord_id = ?", discordID).First(user).Error; err != nil {
+			return err
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientFunds
+		}
+
+		entry.Balance = user.Money
+		return tx.Create(entry).Error
+	})
+
+	return entry, err
+}
+
+// Debit takes the amount from the user, the amount must be positive
+func Debit(db *gorm.DB, discordID string, amount int64, kind LedgerKind, memo string) (*LedgerEntry, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return ApplyLedgerEntry(db, discordID, -amount, kind, memo)
+}
+
+// Credit gives the amount to the user, the amount must be positive
+func Credit(db *gorm.DB, discordID string, amount int64, kind LedgerKind, memo string) (*LedgerEntry, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	return ApplyLedgerEntry(db, discordID, amount, kind, memo)
+}
+
+func GetRecentLedgerEntries(db *gorm.DB, discordID string, limit int) ([]LedgerEntry, error) {
+	log.Debugf("GetRecentLedgerEntries(id: %s, limit: %d)", discordID, limit)
+
+	entries := []LedgerEntry{}
+	result := db.Where("user_discord_id = ?", discordID).Order("id desc").Limit(limit).Find(&entries)
+	return entries, result.Error
+}
+
+// A short human description of the kind of entry
+func (kind LedgerKind) Title() string {
+	switch kind {
+	case LedgerBetPlaced:
+		return "Bet placed"
+	case LedgerBetWon:
+		return "Bet won"
+	case LedgerBetRefund:
+		return "Bet refunded"
+	case LedgerRacePrize:
+		return "Race prize"
+	case LedgerGift:
+		return "Gift"
+	case LedgerPurchase:
+		return "Purchase"
+	case LedgerBreeding:
+		return "Breeding"
+	case LedgerEscrow:
+		return "Escrow"
+	case LedgerTrade:
+		return "Trade"
+	case LedgerAuction:
+		return "Auction"
+	case LedgerRaffle:
+		return "Raffle"
+	case LedgerSale:
+		return "Sale"
+	case LedgerAdmin:
+		return "Adjustment"
+	}
+	return string(kind)
+}
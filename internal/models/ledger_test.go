@@ -0,0 +1,58 @@
+package models
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestConcurrentDebitsNeverOverdraw(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "punter", 90)
+
+	// 20 bets of 7g against 100g, only 14 of them can be afforded
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		debited  int
+		declined int
+	)
+	for index := 0; index < 20; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Debit(db, "punter", 7, LedgerBetPlaced, "test bet")
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch err {
+			case nil:
+				debited++
+			case ErrInsufficientFunds:
+				declined++
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	user, err := GetUserByDiscordID(db, "punter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Money < 0 {
+		t.Fatalf("punter was overdrawn to %dg", user.Money)
+	}
+	if debited != 14 || declined != 6 {
+		t.Errorf("%d debits went through and %d were declined, expected 14 and 6", debited, declined)
+	}
+	if user.Money != 100-int64(debited)*7 {
+		t.Errorf("punter has %dg after %d debits of 7g from 100g", user.Money, debited)
+	}
+
+	var spent int64
+	db.Model(&LedgerEntry{}).Where("kind = ?", LedgerBetPlaced).Select("-sum(amount)").Scan(&spent)
+	if spent != int64(debited)*7 {
+		t.Errorf("ledger records %dg spent, expected %dg", spent, debited*7)
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Mood Constants
+	MoodBiasScale   = 0.1            // The step bias of the happiest snail
+	MoodHalfLife    = 24 * time.Hour // How long it takes a mood to halve
+	MoodLockRaces   = 5              // How many races hype and shell swaps last
+	MoodWinChange   = 0.3
+	MoodPlaceChange = 0.1   // For coming 2nd or 3rd
+	MoodLossChange  = -0.15 // For finishing outside the places
+
+	// A mood has to be at least this strong to show as happy or sad
+	moodThreshold = 0.5
+)
+
+// A short human description of the mood
+func (m SnailMood) Title() string {
+	switch m {
+	case MoodSad:
+		return "😢 Sad"
+	case MoodHappy:
+		return "😄 Happy"
+	case MoodFocused:
+		return "🎯 Focused"
+	}
+	return "😐 Content"
+}
+
+// CurrentMood is the snail's mood from -1 to 1 at the time. Locked moods hold
+// their value, otherwise the mood halves every MoodHalfLife since it was last
+// changed.
+func (s Snail) CurrentMood(now time.Time) float64 {
+	if s.MoodRaces > 0 {
+		switch s.MoodLock {
+		case MoodSad:
+			return -1
+		case MoodHappy, MoodFocused:
+			return 1
+		}
+		return 0
+	}
+
+	elapsed := now.Sub(s.MoodUpdatedAt)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	return s.Mood * math.Pow(0.5, float64(elapsed)/float64(MoodHalfLife))
+}
+
+// The mood the snail is closest to right now
+func (s Snail) MoodLevel() SnailMood {
+	if s.MoodRaces > 0 {
+		return s.MoodLock
+	}
+
+	mood := s.CurrentMood(time.Now())
+	if mood >= moodThreshold {
+		return MoodHappy
+	}
+	if mood <= -moodThreshold {
+		return MoodSad
+	}
+	return MoodNeutral
+}
+
+// Describe the snail's mood, along with how long a locked mood will last
+func (s Snail) RenderMood() string {
+	if s.MoodRaces == 1 {
+		return fmt.Sprintf("%s for the next race", s.MoodLock.Title())
+	}
+	if s.MoodRaces > 1 {
+		return fmt.Sprintf("%s for the next %d races", s.MoodLock.Title(), s.MoodRaces)
+	}
+	return s.MoodLevel().Title()
+}
+
+// How much the mood changes for finishing in the place
+func moodChange(place int) float64 {
+	switch place {
+	case 1:
+		return MoodWinChange
+	case 2, 3:
+		return MoodPlaceChange
+	}
+	return MoodLossChange
+}
+
+// LockMood sets the snail's mood for its next races, replacing any mood that
+// was already locked.
+func LockMood(db *gorm.DB, snailID uint, mood SnailMood, races uint64) error {
+	log.Debugf("LockMood(snail: %d, mood: %d, races: %d)", snailID, mood, races)
+
+	return db.Model(&Snail{}).Where("id = ?", snailID).
+		Updates(map[string]interface{}{"mood_lock": mood, "mood_races": races}).Error
+}
+
+// Update the snail's mood after it finished a race in the place. Locked moods
+// count down instead of changing, and a sad snail cheers up once its sadness
+// wears off.
+func (snail *Snail) updateMood(tx *gorm.DB, place int) error {
+	log.Debugf("updateMood(snail: %s, place: %d)", snail.Name, place)
+
+	// The snail could have been hyped since the moods were locked in, which is
+	// saved for its next race
+	current := &Snail{}
+	if err := tx.Select("id", "mood", "mood_updated_at", "mood_lock", "mood_races").First(current, snail.ID).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{}
+	if current.MoodRaces > 0 {
+		if snail.MoodRaces == 0 {
+			return nil
+		}
+		updates["mood_races"] = current.MoodRaces - 1
+		if current.MoodRaces == 1 {
+			updates["mood_lock"] = MoodNeutral
+			if current.MoodLock == MoodSad {
+				updates["mood"], updates["mood_updated_at"] = 1.0, now
+			}
+		}
+	} else {
+		mood := current.CurrentMood(now) + moodChange(place)
+		updates["mood"], updates["mood_updated_at"] = math.Max(-1, math.Min(1, mood)), now
+	}
+	return tx.Model(&Snail{}).Where("id = ?", snail.ID).Updates(updates).Error
+}
+
+// Set the mood each snail races with, real snails are reloaded as they could
+// have been hyped since they entered. Must be called holding mu.
+func (r *Race) lockInMoods() {
+	now := time.Now()
+	for _, snail := range r.Snails {
+		if snail.ID != 0 && r.DB != nil {
+			current := &Snail{}
+			err := r.DB.Select("id", "mood", "mood_updated_at", "mood_lock", "mood_races").First(current, snail.ID).Error
+			if err != nil {
+				log.WithField("race", r.Id).WithError(err).Warnf("Failed to get the mood of %s", snail.Name)
+			} else {
+				snail.Mood, snail.MoodUpdatedAt = current.Mood, current.MoodUpdatedAt
+				snail.MoodLock, snail.MoodRaces = current.MoodLock, current.MoodRaces
+			}
+		}
+		snail.moodBias = snail.CurrentMood(now) * MoodBiasScale
+	}
+}
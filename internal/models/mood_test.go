@@ -0,0 +1,50 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMoodDecaysToNeutral(t *testing.T) {
+	now := time.Now()
+	snail := Snail{Mood: 1, MoodUpdatedAt: now.Add(-MoodHalfLife)}
+	if mood := snail.CurrentMood(now); math.Abs(mood-0.5) > 0.001 {
+		t.Errorf("mood after a half life is %f", mood)
+	}
+
+	snail.MoodLock, snail.MoodRaces = MoodSad, 2
+	if mood := snail.CurrentMood(now); mood != -1 || snail.MoodLevel() != MoodSad {
+		t.Errorf("locked sad mood is %f", mood)
+	}
+}
+
+func TestMoodAfterRaces(t *testing.T) {
+	db := newTestDB(t)
+	snail := newTestOwnedSnail(t, db, "owner")
+
+	if err := snail.updateMood(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&snail, snail.ID)
+	if math.Abs(snail.Mood-MoodWinChange) > 0.001 {
+		t.Errorf("mood after a win is %f", snail.Mood)
+	}
+
+	// A sad snail counts down its races and cheers up at the end
+	if err := LockMood(db, snail.ID, MoodSad, 2); err != nil {
+		t.Fatal(err)
+	}
+	for race := 0; race < 2; race++ {
+		locked := Snail{}
+		db.First(&locked, snail.ID)
+		if err := locked.updateMood(db, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cheered := Snail{}
+	db.First(&cheered, snail.ID)
+	if cheered.MoodRaces != 0 || cheered.MoodLevel() != MoodHappy {
+		t.Errorf("snail is %s with %d races left after its sadness", cheered.RenderMood(), cheered.MoodRaces)
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// How many generations the pedigree goes back, including the snail itself
+const PedigreeGenerations = 4
+
+// Pedigree is a snail's family tree, the parents are nil if they aren't known
+type Pedigree struct {
+	Snail *Snail
+	Sire  *Pedigree
+	Dam   *Pedigree
+}
+
+// GetPedigree loads the snail's ancestors for the given number of generations.
+// Ancestors that have since been sold are still included.
+func GetPedigree(db *gorm.DB, snail *Snail, generations int) *Pedigree {
+	log.Debugf("GetPedigree(snail: %d, generations: %d)", snail.ID, generations)
+
+	pedigree := &Pedigree{Snail: snail}
+	if generations <= 1 {
+		return pedigree
+	}
+
+	pedigree.Sire = getAncestor(db, snail.SireID, generations-1)
+	pedigree.Dam = getAncestor(db, snail.DamID, generations-1)
+	return pedigree
+}
+
+func getAncestor(db *gorm.DB, id uint, generations int) *Pedigree {
+	if id == 0 {
+		return nil
+	}
+
+	ancestor := &Snail{}
+	if err := db.Unscoped().First(ancestor, id).Error; err != nil {
+		log.WithError(err).Warnf("Failed to get ancestor %d", id)
+		return nil
+	}
+	return GetPedigree(db, ancestor, generations)
+}
@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestGetPedigree(t *testing.T) {
+	db := newTestDB(t)
+
+	// A line of snails, each bred from the one before and an unknown dam
+	line := make([]Snail, PedigreeGenerations+1)
+	for generation := range line {
+		line[generation] = Snail{Name: "snail", OwnerID: "owner", Level: 1}
+		if generation > 0 {
+			line[generation].SireID = line[generation-1].ID
+		}
+		if err := db.Create(&line[generation]).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Sold ancestors are still part of the family
+	if err := db.Delete(&line[2]).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	snail := &line[len(line)-1]
+	pedigree := GetPedigree(db, snail, PedigreeGenerations)
+	depth := 0
+	for node := pedigree; node != nil; node = node.Sire {
+		if node.Dam != nil {
+			t.Errorf("generation %d has an unknown dam", depth)
+		}
+		if want := line[len(line)-1-depth].ID; node.Snail.ID != want {
+			t.Errorf("generation %d is snail %d, want %d", depth, node.Snail.ID, want)
+		}
+		depth++
+	}
+	if depth != PedigreeGenerations {
+		t.Errorf("pedigree goes back %d generations, want %d", depth, PedigreeGenerations)
+	}
+
+	if single := GetPedigree(db, snail, 1); single.Sire != nil || single.Dam != nil {
+		t.Error("single generation pedigree has parents")
+	}
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"os"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// The house cut is configured as a percentage, e.g. `HOUSE_CUT=10`
+	HouseCutEnv     = "HOUSE_CUT"
+	DefaultHouseCut = 0.10
+)
+
+// The house cut taken from parimutuel pools before the winners are paid
+func houseCut() float64 {
+	value := os.Getenv(HouseCutEnv)
+	if value == "" {
+		return DefaultHouseCut
+	}
+
+	percent, err := strconv.ParseFloat(value, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		log.WithField("env", HouseCutEnv).Warnf("Invalid house cut %q, using the default", value)
+		return DefaultHouseCut
+	}
+	return percent / 100.0
+}
+
+// Total money in the win pool and how much of it is on each snail
+func (r *Race) poolStakes() (int, []int) {
+	total, stakes := 0, make([]int, len(r.Snails))
+	for _, bet := range r.Bets {
+		if bet.Type != BetWin {
+			continue
+		}
+		total += bet.Amount
+		stakes[bet.Selections[0]] += bet.Amount
+	}
+	return total, stakes
+}
+
+// The implied odds of each snail based on the current win pool, this is what a
+// winning bet would be multiplied by if betting closed now. Snails with nothing
+// on them have odds of 0.
+func (r *Race) poolOdds() []float64 {
+	total, stakes := r.poolStakes()
+	net := float64(total) * (1.0 - r.HouseCut)
+
+	odds := make([]float64, len(r.Snails))
+	for index, stake := range stakes {
+		if stake > 0 {
+			odds[index] = net / float64(stake)
+		}
+	}
+	return odds
+}
+
+// Each bet type has its own pool. The pool, less the house cut, is split
+// between the winning bets in proportion to their stake. If nobody won a pool
+// then every bet in it is refunded.
+func (r *Race) poolPayouts() []int {
+	payouts := make([]int, len(r.Bets))
+
+	totals, winningStakes := make(map[BetType]int), make(map[BetType]int)
+	for _, bet := range r.Bets {
+		totals[bet.Type] += bet.Amount
+		if r.betWon(bet) {
+			winningStakes[bet.Type] += bet.Amount
+		}
+	}
+
+	for index, bet := range r.Bets {
+		if winningStakes[bet.Type] == 0 {
+			payouts[index] = bet.Amount
+			continue
+		}
+
+		if r.betWon(bet) {
+			net := float64(totals[bet.Type]) * (1.0 - r.HouseCut)
+			payouts[index] = int(net * float64(bet.Amount) / float64(winningStakes[bet.Type]))
+		}
+	}
+	return payouts
+}
+
+// Fixed odds payouts, a winning bet is multiplied by the odds it was placed at
+func (r *Race) fixedOddsPayouts() []int {
+	payouts := make([]int, len(r.Bets))
+	for index, bet := range r.Bets {
+		if r.betWon(bet) {
+			payouts[index] = int(float64(bet.Amount) * bet.Odds)
+		}
+	}
+	return payouts
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+// A race of snails that finished in the order given, by index
+func newTestFinishedRace(positions ...int) *Race {
+	race := &Race{}
+	for _, position := range positions {
+		snail := &Snail{}
+		race.Snails = append(race.Snails, snail)
+		race.Winners = append(race.Winners, RaceSnailPos{Position: position, Frame: position, Snail: snail})
+	}
+	race.sortWinners()
+	return race
+}
+
+func TestHouseCut(t *testing.T) {
+	for value, want := range map[string]float64{
+		"":     DefaultHouseCut,
+		"10":   0.10,
+		"25":   0.25,
+		"2.5":  0.025,
+		"0":    0,
+		"100":  1,
+		"abc":  DefaultHouseCut,
+		"10%":  DefaultHouseCut,
+		"-5":   DefaultHouseCut,
+		"101":  DefaultHouseCut,
+		"0x10": DefaultHouseCut,
+	} {
+		t.Setenv(HouseCutEnv, value)
+		if got := houseCut(); math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s=%q gave a house cut of %v, want %v", HouseCutEnv, value, got, want)
+		}
+	}
+}
+
+func TestPoolPayouts(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		houseCut float64
+		bets     []RaceBet
+		want     []int
+	}{
+		{
+			name:     "winners split the pool by stake",
+			houseCut: 0.10,
+			bets: []RaceBet{
+				{Type: BetWin, Selections: []int{0}, Amount: 30},
+				{Type: BetWin, Selections: []int{0}, Amount: 10},
+				{Type: BetWin, Selections: []int{1}, Amount: 60},
+			},
+			want: []int{67, 22, 0},
+		},
+		{
+			name:     "no house cut",
+			houseCut: 0,
+			bets: []RaceBet{
+				{Type: BetWin, Selections: []int{0}, Amount: 10},
+				{Type: BetWin, Selections: []int{1}, Amount: 10},
+			},
+			want: []int{20, 0},
+		},
+		{
+			name:     "nobody won the pool",
+			houseCut: 0.10,
+			bets: []RaceBet{
+				{Type: BetWin, Selections: []int{1}, Amount: 20},
+				{Type: BetWin, Selections: []int{2}, Amount: 30},
+			},
+			want: []int{20, 30},
+		},
+		{
+			name:     "each bet type has its own pool",
+			houseCut: 0.10,
+			bets: []RaceBet{
+				{Type: BetWin, Selections: []int{0}, Amount: 50},
+				{Type: BetWin, Selections: []int{1}, Amount: 50},
+				{Type: BetPlace, Selections: []int{1}, Amount: 40},
+				{Type: BetPlace, Selections: []int{2}, Amount: 60},
+				{Type: BetExacta, Selections: []int{1, 0}, Amount: 10},
+			},
+			want: []int{90, 0, 90, 0, 10},
+		},
+	} {
+		race := newTestFinishedRace(1, 2, 3)
+		race.HouseCut = test.houseCut
+		race.Bets = test.bets
+
+		payouts := race.poolPayouts()
+		for index, want := range test.want {
+			if payouts[index] != want {
+				t.Errorf("%s: bet %d paid %dg, want %dg", test.name, index, payouts[index], want)
+			}
+		}
+	}
+}
+
+func TestPoolOdds(t *testing.T) {
+	race := newTestFinishedRace(1, 2, 3)
+	race.HouseCut = 0.10
+	race.Bets = []RaceBet{
+		{Type: BetWin, Selections: []int{0}, Amount: 30},
+		{Type: BetWin, Selections: []int{1}, Amount: 10},
+		{Type: BetPlace, Selections: []int{2}, Amount: 50},
+	}
+
+	// Only the win pool counts towards the odds
+	for index, want := range []float64{1.2, 3.6, 0} {
+		if got := race.poolOdds()[index]; math.Abs(got-want) > 1e-9 {
+			t.Errorf("snail %d has pool odds of %v, want %v", index, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import "fmt"
+
+// RaceLength is the length of the track in simulation units, every 100 units
+// is a meter of track.
+type RaceLength int
+
+const (
+	RaceLength1m  RaceLength = 100
+	RaceLength2m  RaceLength = 200
+	RaceLength5m  RaceLength = 500
+	RaceLength10m RaceLength = 1000
+
+	DefaultRaceLength = RaceLength1m
+)
+
+// All the race lengths that can be hosted
+var RaceLengths = []RaceLength{
+	RaceLength1m,
+	RaceLength2m,
+	RaceLength5m,
+	RaceLength10m,
+}
+
+// ParseRaceLength converts the race type given to `/snailrace host`, for
+// example `5m`, into the race length.
+func ParseRaceLength(str string) (RaceLength, bool) {
+	for _, length := range RaceLengths {
+		if length.String() == str {
+			return length, true
+		}
+	}
+	return DefaultRaceLength, false
+}
+
+func (l RaceLength) String() string {
+	return fmt.Sprintf("%dm", l.Meters())
+}
+
+func (l RaceLength) Meters() int {
+	return int(l) / int(RaceLength1m)
+}
+
+// Multiplier is used to scale the XP and money rewards of a race, longer
+// races pay out more.
+func (l RaceLength) Multiplier() int {
+	return l.Meters()
+}
@@ -0,0 +1,374 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/simulation"
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type RaceStatus string
+
+const (
+	RaceStatusOpen      RaceStatus = "open"
+	RaceStatusFinished  RaceStatus = "finished"
+	RaceStatusCancelled RaceStatus = "cancelled"
+)
+
+// RaceRecord is the persisted history of a race. It holds everything needed
+// to replay the race, the seed and the full simulated timeline, along with the
+// odds, bets and payouts for auditing. The record is created as soon as the
+// race is hosted and bets are added as they are placed, so a race that never
+// finished can have its bets refunded.
+type RaceRecord struct {
+	gorm.Model
+
+	RaceID    string     `gorm:"index"`
+	Status    RaceStatus `gorm:"index"`
+	ChannelID string
+	MessageID string
+	HostID    string
+	Length    RaceLength
+	Seed      int64
+
+	NoBets     bool
+	DontFill   bool
+	OnlyOne    bool
+	Parimutuel bool
+	HouseCut   float64
+
+	// JSON encoded simulation.Result of the final attempt of the race
+	Timeline string
+
+	Entrants []RaceRecordEntrant
+	Bets     []RaceRecordBet
+}
+
+// RaceRecordEntrant is a snapshot of a snail as it was when it entered the
+// race. Filler snails have a SnailID of 0.
+type RaceRecordEntrant struct {
+	gorm.Model
+
+	RaceRecordID uint `gorm:"index"`
+	Lane         int
+	SnailID      uint
+	Name         string
+	OwnerID      string
+	Level        uint64
+	Mood         float64    // The mood bias the snail raced with
+	Stats        SnailStats `gorm:"embedded"`
+
+	Odds     float64
+	Position int
+}
+
+type RaceRecordBet struct {
+	gorm.Model
+
+	RaceRecordID  uint `gorm:"index"`
+	UserDiscordId string
+	Type          BetType
+	SnailIndex    int
+	Selections    string
+	Amount        int
+	Odds          float64
+	Payout        int
+}
+
+func newRaceRecordBet(bet RaceBet) RaceRecordBet {
+	return RaceRecordBet{
+		UserDiscordId: bet.UserDiscordId,
+		Type:          bet.Type,
+		SnailIndex:    bet.Selections[0],
+		Selections:    FormatSelections(bet.Selections),
+		Amount:        bet.Amount,
+		Odds:          bet.Odds,
+		Payout:        bet.Payout,
+	}
+}
+
+// Build the record header from the race settings, without the entrants, bets
+// or timeline.
+func newRaceRecordHeader(r *Race, status RaceStatus) *RaceRecord {
+	record := &RaceRecord{
+		RaceID:     r.Id,
+		Status:     status,
+		ChannelID:  r.ChannelId,
+		Length:     r.Length,
+		Seed:       r.Seed,
+		NoBets:     r.NoBets,
+		DontFill:   r.DontFill,
+		OnlyOne:    r.OnlyOne,
+		Parimutuel: r.Parimutuel,
+		HouseCut:   r.HouseCut,
+		Entrants:   make([]RaceRecordEntrant, 0),
+		Bets:       make([]RaceRecordBet, 0),
+	}
+	if r.Host != nil {
+		record.HostID = r.Host.ID
+	}
+	if r.Message != nil {
+		record.MessageID = r.Message.ID
+	}
+	return record
+}
+
+// Build a record from a finished race, this doesn't save the record
+func NewRaceRecord(r *Race) (*RaceRecord, error) {
+	timeline, err := json.Marshal(r.Result)
+	if err != nil {
+		return nil, err
+	}
+
+	record := newRaceRecordHeader(r, RaceStatusFinished)
+	record.Seed = r.Result.Seed
+	record.Timeline = string(timeline)
+
+	for index, snail := range r.Snails {
+		record.Entrants = append(record.Entrants, RaceRecordEntrant{
+			Lane:     index,
+			SnailID:  snail.ID,
+			Name:     snail.Name,
+			OwnerID:  snail.OwnerID,
+			Level:    snail.Level,
+			Mood:     snail.moodBias,
+			Stats:    snail.Stats,
+			Odds:     r.Odds[index],
+			Position: r.racePosPosition(snail),
+		})
+	}
+
+	for _, bet := range r.Bets {
+		betRecord := newRaceRecordBet(bet)
+		betRecord.ID = bet.RecordID
+		record.Bets = append(record.Bets, betRecord)
+	}
+
+	return record, nil
+}
+
+// Create the open record for a race that has just been hosted
+func openRaceRecord(db *gorm.DB, r *Race) error {
+	log.Debugf("openRaceRecord(race: %s)", r.Id)
+
+	record := newRaceRecordHeader(r, RaceStatusOpen)
+	if err := db.Create(record).Error; err != nil {
+		return err
+	}
+
+	r.recordID = record.ID
+	return nil
+}
+
+// Save the finished race, filling in the open record if the race has one. The
+// bets already have records from when they were placed so only their payouts
+// are updated.
+func SaveRaceRecord(db *gorm.DB, r *Race) error {
+	log.Debugf("SaveRaceRecord(race: %s)", r.Id)
+
+	record, err := NewRaceRecord(r)
+	if err != nil {
+		return err
+	}
+
+	if r.recordID == 0 {
+		return db.Create(record).Error
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&RaceRecord{}).
+			Where("id = ? AND status = ?", r.recordID, RaceStatusOpen).
+			Updates(map[string]interface{}{
+				"status":   RaceStatusFinished,
+				"seed":     record.Seed,
+				"timeline": record.Timeline,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("race record %d is no longer open", r.recordID)
+		}
+
+		for _, entrant := range record.Entrants {
+			entrant.RaceRecordID = r.recordID
+			if err := tx.Create(&entrant).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, bet := range record.Bets {
+			bet.RaceRecordID = r.recordID
+			if bet.ID == 0 {
+				if err := tx.Create(&bet).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Model(&bet).Update("payout", bet.Payout).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Get the latest finished record for the race id, race ids are only unique
+// while the race is running so there is a chance of an older record with the
+// same id.
+func GetRaceRecord(db *gorm.DB, raceId string) (*RaceRecord, error) {
+	log.Debugf("GetRaceRecord(race: %s)", raceId)
+
+	record := &RaceRecord{}
+	result := db.Where("race_id = ? AND status = ?", raceId, RaceStatusFinished).
+		Preload("Entrants", func(db *gorm.DB) *gorm.DB { return db.Order("lane") }).
+		Preload("Bets").
+		Order("created_at desc").
+		First(record)
+	return record, result.Error
+}
+
+func RaceRecordExists(db *gorm.DB, raceId string) bool {
+	var count int64
+	db.Model(&RaceRecord{}).Where("race_id = ?", raceId).Count(&count)
+	return count > 0
+}
+
+// Rebuild a race from the record so it can be rendered again. The race is
+// marked as a replay so it never pays out.
+func (record *RaceRecord) Race(channelId string) (*Race, error) {
+	result := &simulation.Result{}
+	if err := json.Unmarshal([]byte(record.Timeline), result); err != nil {
+		return nil, err
+	}
+
+	race := &Race{
+		Id:         record.RaceID,
+		ChannelId:  channelId,
+		Stage:      RaceStageRunning,
+		Length:     record.Length,
+		Seed:       record.Seed,
+		Replay:     true,
+		NoBets:     record.NoBets,
+		DontFill:   record.DontFill,
+		OnlyOne:    record.OnlyOne,
+		Parimutuel: record.Parimutuel,
+		HouseCut:   record.HouseCut,
+		Snails:     make([]*Snail, 0),
+		Bets:       make([]RaceBet, 0),
+		Odds:       make([]float64, 0),
+		Winners:    make([]RaceSnailPos, 0),
+		Result:     result,
+	}
+
+	for _, entrant := range record.Entrants {
+		race.Snails = append(race.Snails, &Snail{
+			Name:     entrant.Name,
+			OwnerID:  entrant.OwnerID,
+			Level:    entrant.Level,
+			moodBias: entrant.Mood,
+			Stats:    entrant.Stats,
+		})
+		race.Odds = append(race.Odds, entrant.Odds)
+	}
+
+	return race, nil
+}
+
+// ReplayRace re-renders a finished race from its record in the given channel
+func ReplayRace(s *discordgo.Session, record *RaceRecord, channelId string) {
+	race, err := record.Race(channelId)
+	if err != nil {
+		log.WithField("race", record.RaceID).WithError(err).Warn("Failed to rebuild race from record")
+		return
+	}
+
+	replayStart := time.Now()
+	defer func() {
+		log.WithField("race", race.Id).Infof("Replay took %s", time.Since(replayStart))
+	}()
+
+	log.WithFields(log.Fields{"race": race.Id, "seed": race.Seed}).Info("Replaying a race")
+	if race.setupMessage(s) != nil {
+		return
+	}
+
+	race.playback(s, race.Result)
+
+	race.sortWinners()
+	race.Stage = RaceStageFinished
+	race.Render(s)
+}
+
+// Cancel an open race record, refunding every bet on it and failing its hypes.
+// The status is only changed if the record is still open so a race can't be
+// refunded twice.
+func CancelRaceRecord(db *gorm.DB, record *RaceRecord) error {
+	log.Debugf("CancelRaceRecord(race: %s)", record.RaceID)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&RaceRecord{}).
+			Where("id = ? AND status = ?", record.ID, RaceStatusOpen).
+			Update("status", RaceStatusCancelled)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("race record %d is no longer open", record.ID)
+		}
+
+		bets := []RaceRecordBet{}
+		if err := tx.Where("race_record_id = ?", record.ID).Find(&bets).Error; err != nil {
+			return err
+		}
+
+		memo := fmt.Sprintf("Refunded bet on cancelled race %s", record.RaceID)
+		for _, bet := range bets {
+			if _, err := Credit(tx, bet.UserDiscordId, int64(bet.Amount), LedgerBetRefund, memo); err != nil {
+				return err
+			}
+			if err := tx.Model(&bet).Update("payout", bet.Amount).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := failRaceHypes(tx, record.RaceID); err != nil {
+			return err
+		}
+
+		record.Status = RaceStatusCancelled
+		return nil
+	})
+}
+
+// RecoverRaces cancels the races that were still running when the bot went
+// down, refunding their bets and letting the channel know on the race message.
+func RecoverRaces(s *discordgo.Session, db *gorm.DB) {
+	records := []RaceRecord{}
+	if err := db.Where("status = ?", RaceStatusOpen).Find(&records).Error; err != nil {
+		log.WithError(err).Error("Failed to find unfinished races")
+		return
+	}
+
+	for index := range records {
+		record := &records[index]
+		if err := CancelRaceRecord(db, record); err != nil {
+			log.WithField("race", record.RaceID).WithError(err).Error("Failed to cancel unfinished race")
+			continue
+		}
+		log.WithField("race", record.RaceID).Info("Cancelled unfinished race")
+
+		if record.MessageID == "" {
+			continue
+		}
+		edit := cancelledRaceEdit(record.ChannelID, record.MessageID, record.RaceID, "The race was interrupted by a restart.")
+		if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+			log.WithField("race", record.RaceID).WithError(err).Warn("Failed to render cancelled race")
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestCancelRaceRecordRefundsBets(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "punter", 100)
+
+	race := newTestRace(t, db)
+	race.AddSnail(newTestSnail(t, db, 1, "owner"))
+	race.AddSnail(newTestSnail(t, db, 2, "owner"))
+	race.closeEntries()
+	if err := race.PlaceBet(BetWin, []int{0}, 30, "punter"); err != nil {
+		t.Fatal(err)
+	}
+	if err := race.PlaceBet(BetPlace, []int{1}, 20, "punter"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The bot goes down here, leaving the record open
+	record := &RaceRecord{}
+	if err := db.Where("status = ?", RaceStatusOpen).First(record).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := CancelRaceRecord(db, record); err != nil {
+		t.Fatal(err)
+	}
+	if err := CancelRaceRecord(db, record); err == nil {
+		t.Error("cancelled the same race twice")
+	}
+
+	var bets, refunds int64
+	db.Model(&LedgerEntry{}).Where("kind = ?", LedgerBetPlaced).Select("-sum(amount)").Scan(&bets)
+	db.Model(&LedgerEntry{}).Where("kind = ?", LedgerBetRefund).Select("sum(amount)").Scan(&refunds)
+	if bets != 50 || refunds != 50 {
+		t.Errorf("punter bet %dg and was refunded %dg, expected 50g for both", bets, refunds)
+	}
+}
+
+func TestFailedPayoutRefundsRace(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	newTestUser(t, db, "punter", 100)
+	snails := []Snail{newTestOwnedSnail(t, db, "owner"), newTestOwnedSnail(t, db, "owner")}
+	for index := range snails {
+		snails[index].Stats.GenerateStats(StartingSnail)
+	}
+
+	race := newTestRace(t, db)
+	race.AddSnail(&snails[0])
+	race.AddSnail(&snails[1])
+	race.closeEntries()
+	if err := race.PlaceBet(BetWin, []int{0}, 30, "punter"); err != nil {
+		t.Fatal(err)
+	}
+	race.Winners = []RaceSnailPos{{Position: 1, Snail: race.Snails[0]}, {Position: 2, Snail: race.Snails[1]}}
+	race.Message = nil
+
+	// The snails can't be updated, so nothing from the race should be kept
+	db.Callback().Update().Before("gorm:update").Register("fail_snails", func(tx *gorm.DB) {
+		if tx.Statement.Table == "snails" {
+			tx.AddError(errors.New("snails are read only"))
+		}
+	})
+	race.finish(nil)
+
+	if race.Stage != RaceStageCancelled {
+		t.Errorf("race is at stage %d after a failed payout, expected cancelled", race.Stage)
+	}
+	record := &RaceRecord{}
+	if err := db.First(record, race.recordID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if record.Status != RaceStatusCancelled {
+		t.Errorf("race record is %v after a failed payout, expected cancelled", record.Status)
+	}
+	owner, _ := GetUserByDiscordID(db, "owner")
+	if owner.Money != 11 || owner.Races != 0 {
+		t.Errorf("owner has %dg from %d races after a failed payout", owner.Money, owner.Races)
+	}
+	punter, _ := GetUserByDiscordID(db, "punter")
+	if punter.Money != 110 {
+		t.Errorf("punter has %dg after a failed payout, expected their 110g back", punter.Money)
+	}
+	winner := Snail{}
+	db.First(&winner, snails[0].ID)
+	if winner.Races != 0 || winner.Exp != 0 {
+		t.Errorf("winner has %d races and %d xp after a failed payout", winner.Races, winner.Exp)
+	}
+	if race.Bets[0].Payout != 0 {
+		t.Errorf("bet shows a payout of %dg that was never paid", race.Bets[0].Payout)
+	}
+}
@@ -2,10 +2,13 @@ package models
 
 import (
 	"fmt"
+	"math/rand"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/lcox74/snailrace/internal/simulation"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
@@ -18,6 +21,7 @@ const (
 	RaceStageBetting
 	RaceStageRunning
 	RaceStageFinished
+	RaceStageCancelled
 
 	// State Timeout Constants
 	RaceOpenTimeout      = 10 * time.Second
@@ -26,10 +30,20 @@ const (
 	RaceStepInterval     = 1 * time.Second
 	RaceTimeout          = 10 * time.Minute
 
+	// Number of events that can queue up before the race goroutine handles
+	// them, extra events are dropped as the next render covers them anyway.
+	RaceEventBuffer = 32
+
+	// Entrant Constants
+	MaxRaceEntrants = 10
+	MaxUserEntrants = 3
+
 	// Action Ids
 	RaceActionJoin      = "host_join"
 	RaceActionBet       = "host_bet"
 	RaceActionBetAmount = "host_bet_amout"
+	RaceActionBetType   = "host_bet_type"
+	RaceActionBetPick   = "host_bet_pick"
 
 	// Reward Constants
 	BaseMoney = 10
@@ -44,14 +58,37 @@ var (
 	ErrRaceClosed    = fmt.Errorf("race is closed")
 	ErrRaceFull      = fmt.Errorf("race is full")
 	ErrAlreadyJoined = fmt.Errorf("snail already joined")
+	ErrUserCap       = fmt.Errorf("too many snails from the same owner")
 	ErrNotEnough     = fmt.Errorf("not enough racers")
 	ErrBetsClosed    = fmt.Errorf("bets are closed")
+	ErrRaceCancelled = fmt.Errorf("race was cancelled")
 )
 
+// RaceBet is a bet on the outcome of the race. Selections are the snail
+// indexes picked, in finishing order for exacta and trifecta bets.
 type RaceBet struct {
+	RecordID      uint
 	UserDiscordId string
+	Type          BetType
+	Selections    []int
 	Amount        int
-	SnailIndex    int
+	Odds          float64
+	Payout        int
+	AllIn         bool // The bet took all of the user's money
+}
+
+type RaceEventKind uint8
+
+const (
+	RaceEventJoined RaceEventKind = iota
+	RaceEventBet
+)
+
+// RaceEvent tells the race goroutine that something changed from one of the
+// interaction handlers, the race goroutine is the only one that renders.
+type RaceEvent struct {
+	Kind          RaceEventKind
+	UserDiscordId string
 }
 
 type RaceSnailPos struct {
@@ -59,10 +96,18 @@ type RaceSnailPos struct {
 	Frame    int
 	Snail    *Snail
 }
+
+// Race is shared between the race goroutine and the interaction handlers, the
+// entrants, bets, stage and winners must only be touched while holding mu.
 type Race struct {
+	mu sync.RWMutex
+
 	Id        string
+	GuildId   string
 	ChannelId string
 	Stage     RaceStage
+	Length    RaceLength
+	Seed      int64
 	EndRace   func()
 	DB        *gorm.DB
 
@@ -72,26 +117,98 @@ type Race struct {
 	NoBets   bool
 	DontFill bool
 	OnlyOne  bool
+	Replay   bool
+
+	// Parimutuel races pool all the bets and split them between the winners,
+	// less the house cut, instead of paying out fixed odds.
+	Parimutuel bool
+	HouseCut   float64
 
 	Snails  []*Snail
 	Bets    []RaceBet
 	Odds    []float64
 	Winners []RaceSnailPos
+	Result  *simulation.Result
+
+	Events chan RaceEvent
+
+	// The persisted record of the race, created once the race is hosted
+	recordID uint
+
+	// Closed when the race is cancelled, the race goroutine then refunds the
+	// bets and stops at the next wait
+	cancelled    chan struct{}
+	cancelOnce   sync.Once
+	cancelReason string
 }
 
-func (r *Race) SetupNewRace(id string, channelId string, db *gorm.DB, host *discordgo.User, endRace func()) {
+func (r *Race) SetupNewRace(id string, guildId string, channelId string, db *gorm.DB, host *discordgo.User, endRace func()) {
 	r.Id = id
+	r.GuildId = guildId
 	r.ChannelId = channelId
 	r.Host = host
 	r.EndRace = endRace
 	r.Stage = RaceStageOpen
+	r.Length = DefaultRaceLength
+	r.Seed = rand.Int63()
 	r.Snails = make([]*Snail, 0)
 	r.Bets = make([]RaceBet, 0)
 	r.Odds = make([]float64, 0)
 	r.Winners = make([]RaceSnailPos, 0)
+	r.Events = make(chan RaceEvent, RaceEventBuffer)
+	r.cancelled = make(chan struct{})
 	r.DB = db
 }
 
+// Cancel asks the race to stop, the race goroutine handles the refunds. Races
+// that have already finished running are left to pay out as normal.
+func (r *Race) Cancel(reason string) {
+	r.cancelOnce.Do(func() {
+		r.mu.Lock()
+		r.cancelReason = reason
+		r.mu.Unlock()
+		close(r.cancelled)
+	})
+}
+
+// Let the race goroutine know something has changed without blocking the
+// handler, if the buffer is full a render is already on its way.
+func (r *Race) notify(event RaceEvent) {
+	if r.Events == nil {
+		return
+	}
+	select {
+	case r.Events <- event:
+	default:
+	}
+}
+
+// Waits out a stage of the race, re-rendering whenever a handler reports a
+// change so the message stays up to date. Returns ErrRaceCancelled if the race
+// was cancelled while waiting.
+func (r *Race) wait(s *discordgo.Session, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			return nil
+		case <-r.cancelled:
+			return ErrRaceCancelled
+		case event := <-r.Events:
+			log.WithFields(log.Fields{"race": r.Id, "event": event.Kind, "user": event.UserDiscordId}).Debug("Race event")
+			r.Render(s)
+		}
+	}
+}
+
+func (r *Race) setStage(stage RaceStage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Stage = stage
+}
+
 // Flag setters
 func (r *Race) SetNoBets() {
 	r.NoBets = true
@@ -102,6 +219,13 @@ func (r *Race) SetDontFill() {
 func (r *Race) SetOnlyOne() {
 	r.OnlyOne = true
 }
+func (r *Race) SetParimutuel() {
+	r.Parimutuel = true
+	r.HouseCut = houseCut()
+}
+func (r *Race) SetLength(length RaceLength) {
+	r.Length = length
+}
 
 // If the race doesn't have the dont-fill flag, and the race has less than 4
 // racers, then generate random snails to meet the 4 racer requirement.
@@ -117,52 +241,185 @@ func (r *Race) autoFillRace() {
 }
 
 func (r *Race) AddSnail(snail *Snail) error {
+	committingSnails.Lock()
+	defer committingSnails.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.Stage != RaceStageOpen {
 		return ErrRaceClosed
 	}
 
+	if snail.InEscrow() || !r.snailAvailable(snail) {
+		return ErrSnailInEscrow
+	}
+
+	owned := 0
 	for _, s := range r.Snails {
 		if s.ID == snail.ID {
 			return ErrAlreadyJoined
 		}
+		if s.OwnerID == snail.OwnerID {
+			owned++
+		}
 	}
 
-	if len(r.Snails) >= 10 {
+	if len(r.Snails) >= MaxRaceEntrants {
 		return ErrRaceFull
 	}
 
+	if owned >= MaxUserEntrants {
+		return ErrUserCap
+	}
+
 	r.Snails = append(r.Snails, snail)
+	r.notify(RaceEvent{Kind: RaceEventJoined, UserDiscordId: snail.OwnerID})
 	return nil
 }
 
+// Whether the snail is still the owner's and not held in escrow, it may have
+// been traded or offered up since it was looked up. Dummy snails are always
+// available.
+func (r *Race) snailAvailable(snail *Snail) bool {
+	if snail.ID == 0 {
+		return true
+	}
+
+	var count int64
+	err := r.DB.Model(&Snail{}).Where("id = ? AND owner_id = ? AND escrow = ''", snail.ID, snail.OwnerID).Count(&count).Error
+	if err != nil {
+		log.WithField("race", r.Id).WithError(err).Warnf("Failed to check snail %d can race", snail.ID)
+		return false
+	}
+	return count == 1
+}
+
 func (r *Race) GetSnail(index int) *Snail {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	if index < 0 || index >= len(r.Snails) {
 		return nil
 	}
 
 	return r.Snails[index]
 }
-func (r *Race) PlaceBet(index int, amount int, userDiscordId string) error {
+
+// Whether any of the snails have entered the race and it hasn't finished
+func (r *Race) hasSnail(ids ...uint) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.Stage == RaceStageFinished || r.Stage == RaceStageCancelled {
+		return false
+	}
+	for _, snail := range r.Snails {
+		for _, id := range ids {
+			if snail.ID != 0 && snail.ID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether the race is still taking entries
+func (r *Race) isOpen() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.Stage == RaceStageOpen
+}
+
+// A copy of the entrants so handlers can list them without holding the lock.
+func (r *Race) GetSnails() []*Snail {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snails := make([]*Snail, len(r.Snails))
+	copy(snails, r.Snails)
+	return snails
+}
+
+// Places the bet and takes the money for it, ErrInsufficientFunds is returned
+// if the user can't afford it.
+func (r *Race) PlaceBet(betType BetType, selections []int, amount int, userDiscordId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	if r.Stage != RaceStageBetting || r.NoBets {
 		return ErrBetsClosed
 	}
 
-	if index < 0 || index >= len(r.Snails) {
+	if !r.validSelections(betType, selections) {
 		return ErrInvalidSnail
 	}
 
-	if len(r.Snails) < 2 {
+	if len(r.Snails) < 2 || len(r.Snails) < betType.Picks() {
 		return ErrNotEnough
 	}
 
-	r.Bets = append(r.Bets, RaceBet{
+	bet := RaceBet{
 		UserDiscordId: userDiscordId,
+		Type:          betType,
+		Selections:    selections,
 		Amount:        amount,
-		SnailIndex:    index,
+		Odds:          r.betOdds(betType, selections),
+	}
+
+	// Take the money and record the bet together, the ledger checks the
+	// balance as part of the same update so two bets can't spend the same
+	// money, and a restart can always refund it.
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		memo := fmt.Sprintf("%s bet on race %s", betType.Title(), r.Id)
+		entry, err := Debit(tx, userDiscordId, int64(amount), LedgerBetPlaced, memo)
+		if err != nil {
+			return err
+		}
+		bet.AllIn = entry.Balance == 0
+
+		record := newRaceRecordBet(bet)
+		record.RaceRecordID = r.recordID
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+		bet.RecordID = record.ID
+		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	r.Bets = append(r.Bets, bet)
+	r.notify(RaceEvent{Kind: RaceEventBet, UserDiscordId: userDiscordId})
 	return nil
 }
 
+// Closes entries and opens betting, filling the race and setting the odds
+// now that the entrants are known.
+func (r *Race) closeEntries() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Stage = RaceStageBetting
+	r.autoFillRace()
+	r.lockInMoods()
+	r.generateOdds()
+}
+
+// Closes betting and starts the race, locking in the pool odds now that no
+// more bets can come in.
+func (r *Race) closeBets() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Stage = RaceStageRunning
+	if r.Parimutuel {
+		r.Odds = r.poolOdds()
+	}
+}
+
 func StartRace(s *discordgo.Session, race *Race) {
 	defer race.EndRace()
 
@@ -171,107 +428,206 @@ func StartRace(s *discordgo.Session, race *Race) {
 		log.WithField("race", race.Id).Infof("Race took %s", time.Since(raceStart))
 	}()
 
-	log.WithField("race", race.Id).Info("Starting a race")
-	race.Stage = RaceStageOpen
+	log.WithFields(log.Fields{"race": race.Id, "seed": race.Seed}).Info("Starting a race")
+	race.setStage(RaceStageOpen)
 	if race.setupMessage(s) != nil {
 		return
 	}
 
+	// Record the race before anyone can bet on it, so the bets can be
+	// refunded if the bot goes down mid race
+	if err := openRaceRecord(race.DB, race); err != nil {
+		log.WithField("race", race.Id).WithError(err).Error("Failed to record race")
+		race.renderCancelled(s, "Something went wrong setting up the race, please host it again.")
+		return
+	}
+
 	// Open Stage
 	race.Render(s)
-	time.Sleep(RaceOpenTimeout)
-	race.Stage = RaceStageBetting
-
-	// Autofill the Race
-	if !race.DontFill {
-		race.autoFillRace()
+	if race.wait(s, RaceOpenTimeout) != nil {
+		race.abort(s)
+		return
 	}
-	race.generateOdds()
 
-	for _, snail := range race.Snails {
+	// Autofill the Race and set the odds
+	race.closeEntries()
+
+	for _, snail := range race.GetSnails() {
 		log.WithFields(log.Fields{
 			"race":     race.Id,
 			"snail":    snail.Name,
 			"speed":    snail.Stats.Speed,
 			"stamina":  snail.Stats.Stamina,
 			"recovery": snail.Stats.Recovery,
+			"weight":   snail.Stats.Weight,
 		}).Debug("Entrant stats")
 	}
 
 	// Betting Stage
 	race.Render(s)
+	timeout := RaceBettingTimeout
 	if race.NoBets {
-		time.Sleep(RaceNoBettingTimeout)
-	} else {
-		time.Sleep(RaceBettingTimeout)
+		timeout = RaceNoBettingTimeout
 	}
-	race.Stage = RaceStageRunning
+	if race.wait(s, timeout) != nil {
+		race.abort(s)
+		return
+	}
+	race.closeBets()
 
-	// Race Stage
+	// Race Stage, each attempt is simulated up front from the race seed and
+	// then played back frame by frame.
 	firstRace, raceAttempt := true, 0
 	for firstRace || (race.racePosTie() && race.OnlyOne && raceAttempt < 5) {
-		race.Render(s)
 		firstRace = false
+		race.Result = simulation.Run(race.Seed+int64(raceAttempt), float64(race.Length), race.entrants())
 		raceAttempt++
-		race.Winners = make([]RaceSnailPos, 0)
 
-		// Reset the snails to start at the beginning
-		for _, snail := range race.Snails {
-			snail.NewRace()
+		if race.playback(s, race.Result) != nil {
+			race.abort(s)
+			return
 		}
+	}
+
+	// Finished Stage, nothing else can change the entrants or bets now
+	race.mu.Lock()
+	race.sortWinners()
+	race.Stage = RaceStageFinished
+	race.mu.Unlock()
+	race.finish(s)
+}
 
-		snailsFinished, frame := 0, 0
+// The entrants of the race in the form the simulation expects, indexed the same
+// as the race snails.
+func (r *Race) entrants() []simulation.Entrant {
+	entrants := make([]simulation.Entrant, len(r.Snails))
+	for index, snail := range r.Snails {
+		entrants[index] = snail.Entrant()
+	}
+	return entrants
+}
 
-		// Race until all snails have finished
-		requiredFinished := len(race.Snails)
-		for snailsFinished < requiredFinished {
-			snailsFinished = 0
-			for _, snail := range race.Snails {
+// Plays back a simulated race on the race message, moving each snail along the
+// track and adding it to the winners as it crosses the line. Longer races skip
+// frames so each race takes roughly the same time to watch.
+func (r *Race) playback(s *discordgo.Session, result *simulation.Result) error {
+	r.mu.Lock()
+	r.Winners = make([]RaceSnailPos, 0)
+	for _, snail := range r.Snails {
+		snail.racePosition = 0
+	}
+	r.mu.Unlock()
+	r.Render(s)
+
+	renderEvery := r.Length.Meters()
+	for frame, snapshot := range result.Frames {
+		r.mu.Lock()
+		for index, snail := range r.Snails {
+			snail.racePosition = snapshot.Positions[index]
+		}
 
-				// Check if snail is already finished
-				if snail.racePosition >= float64(MaxRaceLength) {
-					snailsFinished++
-					continue
-				}
+		for _, placing := range result.Placings {
+			if placing.Frame == frame {
+				r.Winners = append(r.Winners, RaceSnailPos{
+					Position: placing.Position,
+					Frame:    placing.Frame,
+					Snail:    r.Snails[placing.Entrant],
+				})
+			}
+		}
+		r.mu.Unlock()
 
-				// Step snail forward
-				snail.Step()
-				if snail.racePosition >= float64(MaxRaceLength) {
-					snailsFinished++
-					race.racePosAdd(snail, frame)
-				}
+		if frame%renderEvery == renderEvery-1 || frame == len(result.Frames)-1 {
+			r.Render(s)
+			if err := r.wait(s, RaceStepInterval); err != nil {
+				return err
 			}
-			race.Render(s)
-			frame++
-			time.Sleep(RaceStepInterval)
 		}
 	}
+	return nil
+}
 
-	// Finished Stage
-	race.sortWinners()
-	race.Stage = RaceStageFinished
-	race.Payout(s)
-	race.Render(s)
+// Stops a cancelled race, closing it to joins and bets before refunding
+// everything that was bet on it.
+func (r *Race) abort(s *discordgo.Session) {
+	r.mu.RLock()
+	reason := r.cancelReason
+	r.mu.RUnlock()
+
+	log.WithField("race", r.Id).Infof("Race was cancelled: %s", reason)
+	r.refund(s, reason)
+}
+
+// Settles the finished race and shows the result. If it can't be settled the
+// race is cancelled and every bet refunded straight away, rather than holding
+// the stakes until the race is recovered after a restart.
+func (r *Race) finish(s *discordgo.Session) {
+	if err := r.Payout(s); err != nil {
+		log.WithField("race", r.Id).WithError(err).Error("Failed to settle race")
+		r.refund(s, "Something went wrong paying out the race, every bet has been refunded.")
+		return
+	}
+	r.Render(s)
+}
+
+// Cancels the race, refunding everything that was bet on it, and shows why on
+// the race message.
+func (r *Race) refund(s *discordgo.Session, reason string) {
+	r.mu.Lock()
+	r.Stage = RaceStageCancelled
+	r.mu.Unlock()
+
+	record := &RaceRecord{RaceID: r.Id}
+	record.ID = r.recordID
+	if err := CancelRaceRecord(r.DB, record); err != nil {
+		log.WithField("race", r.Id).WithError(err).Error("Failed to refund cancelled race")
+	}
+	r.renderCancelled(s, reason)
 }
 
+// Renders the race message for the current stage. The edit is built under
+// the lock but sent after releasing it so handlers aren't held up by Discord.
 func (r *Race) Render(s *discordgo.Session) {
+	edit := r.renderEdit()
+	if edit == nil {
+		return
+	}
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		log.WithField("race", r.Id).WithError(err).Warn("Failed to render race")
+	}
+}
+
+func (r *Race) renderEdit() *discordgo.MessageEdit {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	switch r.Stage {
 	case RaceStageOpen:
-		r.renderOpenRace(s)
+		return r.renderOpenRace()
 	case RaceStageBetting:
-		r.renderBetting(s)
+		return r.renderBetting()
 	case RaceStageRunning:
-		r.renderRunning(s)
+		return r.renderRunning()
 	case RaceStageFinished:
-		r.renderFinished(s)
+		return r.renderFinished()
 	}
+	return nil
+}
+
+// Replays reuse the race rendering, so make it clear in the title that this
+// isn't a live race.
+func (r *Race) title(title string) string {
+	if r.Replay {
+		return "[Replay] " + title
+	}
+	return title
 }
 
 func (r *Race) setupMessage(s *discordgo.Session) (err error) {
 	r.Message, err = s.ChannelMessageSendComplex(r.ChannelId, &discordgo.MessageSend{
 		Embeds: []*discordgo.MessageEmbed{
 			{
-				Title:       "Here Comes a New Race!",
+				Title:       r.title("Here Comes a New Race!"),
 				Description: "Loading...",
 				Color:       0x2ecc71,
 			},
@@ -285,11 +641,12 @@ func (r *Race) setupMessage(s *discordgo.Session) (err error) {
 	return err
 }
 
-func (r *Race) renderOpenRace(s *discordgo.Session) {
+func (r *Race) renderOpenRace() *discordgo.MessageEdit {
 	// Build the Embed Message
 	title := "Race: Open"
 	body := fmt.Sprintf(
-		"A new race has been hosted by %s\n\nRace ID: `%s`\n\nTo join via command, enter the following:\n```\n/snailrace join race_id: %s\n```\n**Entrants: (%d/10)**\n",
+		"A new %s race has been hosted by %s\n\nRace ID: `%s`\n\nTo join via command, enter the following:\n```\n/snailrace join race_id: %s\n```\n**Entrants: (%d/10)**\n",
+		r.Length,
 		r.Host.Username,
 		r.Id,
 		r.Id,
@@ -322,21 +679,30 @@ func (r *Race) renderOpenRace(s *discordgo.Session) {
 			},
 		},
 	}
-	s.ChannelMessageEditComplex(edit)
+	return edit
 }
 
-func (r *Race) renderBetting(s *discordgo.Session) {
+func (r *Race) renderBetting() *discordgo.MessageEdit {
 
 	if r.NoBets {
-		r.renderNoBetting(s)
-		return
+		return r.renderNoBetting()
 	}
 
 	// Build the Embed Message
 	title := "Race: Bets are Open"
+
+	// Parimutuel races show the live implied odds from the pool
+	odds, pool := r.Odds, ""
+	if r.Parimutuel {
+		total, _ := r.poolStakes()
+		odds = r.poolOdds()
+		pool = fmt.Sprintf("**Pool:** %dg (%.0f%% house cut)\n", total, r.HouseCut*100)
+	}
+
 	body := fmt.Sprintf(
-		"Bets are now open to everyone, do you feel lucky? To place a bet you can select the snail via the drop down. Here are the entrants:\n\nRace ID: `%s`\n\n**Entrants: (%d/10)**\n",
+		"Bets are now open to everyone, do you feel lucky? To place a bet you can select the snail via the drop down. Here are the entrants:\n\nRace ID: `%s`\n\n%s**Entrants: (%d/10)**\n",
 		r.Id,
+		pool,
 		len(r.Snails),
 	)
 
@@ -344,7 +710,7 @@ func (r *Race) renderBetting(s *discordgo.Session) {
 
 	// Add the snails to the body as entrants `index - <oods> <snail_name>(<@owner_id>)`
 	for index, snail := range r.Snails {
-		body += fmt.Sprintf("`[%d]: %.02f` %s\n", index, r.Odds[index], snail.renderName(false))
+		body += fmt.Sprintf("`[%d]: %.02f` %s\n", index, odds[index], snail.renderName(false))
 		select_options = append(
 			select_options,
 			discordgo.SelectMenuOption{
@@ -368,18 +734,46 @@ func (r *Race) renderBetting(s *discordgo.Session) {
 		discordgo.ActionsRow{
 			Components: []discordgo.MessageComponent{
 				discordgo.SelectMenu{
-					MenuType: discordgo.StringSelectMenu,
-					CustomID: fmt.Sprintf("%s:%s", RaceActionBet, r.Id),
-					Options:  select_options,
+					MenuType:    discordgo.StringSelectMenu,
+					CustomID:    fmt.Sprintf("%s:%s", RaceActionBet, r.Id),
+					Placeholder: "Bet on a snail to win",
+					Options:     select_options,
+				},
+			},
+		},
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.SelectMenu{
+					MenuType:    discordgo.StringSelectMenu,
+					CustomID:    fmt.Sprintf("%s:%s", RaceActionBetType, r.Id),
+					Placeholder: "Place an exotic bet",
+					Options:     exoticBetOptions(),
 				},
 			},
 		},
 	}
 
-	s.ChannelMessageEditComplex(edit)
+	return edit
 }
 
-func (r *Race) renderNoBetting(s *discordgo.Session) {
+// The exotic bet types for the bet type drop down, win bets are placed with
+// the snail drop down.
+func exoticBetOptions() []discordgo.SelectMenuOption {
+	options := make([]discordgo.SelectMenuOption, 0)
+	for _, betType := range BetTypes {
+		if betType == BetWin {
+			continue
+		}
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       betType.Title(),
+			Description: betType.Description(),
+			Value:       string(betType),
+		})
+	}
+	return options
+}
+
+func (r *Race) renderNoBetting() *discordgo.MessageEdit {
 	// Build the Embed Message
 	title := "Race: Ready to Race"
 	body := fmt.Sprintf(
@@ -405,22 +799,22 @@ func (r *Race) renderNoBetting(s *discordgo.Session) {
 	}
 	edit.Components = []discordgo.MessageComponent{}
 
-	s.ChannelMessageEditComplex(edit)
+	return edit
 }
 
-func (r *Race) renderRunning(s *discordgo.Session) {
-	title := "Race: Racing"
+func (r *Race) renderRunning() *discordgo.MessageEdit {
+	title := r.title("Race: Racing")
 	body := ""
 
 	entrants := fmt.Sprintf("**Entrants: (%d/10):**\n", len(r.Snails))
 
-	track := fmt.Sprintf("```\nRace ID: %s\n\n", r.Id)
+	track := fmt.Sprintf("```\nRace ID: %s (%s)\n\n", r.Id, r.Length)
 	track += "                          🏁\n"
 	track += "  |-----------------------|\n"
 
 	// Build snails
 	for index, snail := range r.Snails {
-		line := snail.renderPosition()
+		line := snail.renderPosition(r.Length)
 
 		// Render the snail on the track
 		row := fmt.Sprintf("%2d| %s | \n", index, line)
@@ -449,21 +843,21 @@ func (r *Race) renderRunning(s *discordgo.Session) {
 	}
 	edit.Components = []discordgo.MessageComponent{}
 
-	s.ChannelMessageEditComplex(edit)
+	return edit
 }
-func (r *Race) renderFinished(s *discordgo.Session) {
-	title := "Race: Complete"
+func (r *Race) renderFinished() *discordgo.MessageEdit {
+	title := r.title("Race: Complete")
 	body := r.getWinnersStr() + "\n\n"
 
 	entrants := fmt.Sprintf("**Entrants: (%d/10):**\n", len(r.Snails))
 
-	track := fmt.Sprintf("```\nRace ID: %s\n\n", r.Id)
+	track := fmt.Sprintf("```\nRace ID: %s (%s)\n\n", r.Id, r.Length)
 	track += "                          🏁\n"
 	track += "  |-----------------------|\n"
 
 	// Build snails
 	for index, snail := range r.Snails {
-		line := snail.renderPosition()
+		line := snail.renderPosition(r.Length)
 
 		// Render the snail on the track
 		row := fmt.Sprintf("%2d| %s |\n", index, line)
@@ -506,11 +900,34 @@ func (r *Race) renderFinished(s *discordgo.Session) {
 	}
 	edit.Components = []discordgo.MessageComponent{}
 
-	s.ChannelMessageEditComplex(edit)
+	return edit
+}
+
+// Replaces the race message with a cancellation notice, this is sent straight
+// away as the race isn't going to render again.
+func (r *Race) renderCancelled(s *discordgo.Session, reason string) {
+	if r.Message == nil {
+		return
+	}
+	if _, err := s.ChannelMessageEditComplex(cancelledRaceEdit(r.ChannelId, r.Message.ID, r.Id, reason)); err != nil {
+		log.WithField("race", r.Id).WithError(err).Warn("Failed to render cancelled race")
+	}
+}
 
+func cancelledRaceEdit(channelId string, messageId string, raceId string, reason string) *discordgo.MessageEdit {
+	edit := discordgo.NewMessageEdit(channelId, messageId)
+	edit.Embeds = []*discordgo.MessageEmbed{
+		{
+			Title:       "Race: Cancelled",
+			Description: fmt.Sprintf("Race `%s` has been cancelled. %s\n\nAny bets placed on this race have been refunded.", raceId, reason),
+			Color:       0xe74c3c,
+		},
+	}
+	edit.Components = []discordgo.MessageComponent{}
+	return edit
 }
 
-func (r Race) getWinnersStr() string {
+func (r *Race) getWinnersStr() string {
 	winners := make([]*Snail, 0)
 	for _, racePos := range r.Winners {
 		if racePos.Position == 1 {
@@ -535,54 +952,126 @@ func (r Race) getWinnersStr() string {
 	return winStr
 }
 
-func (r *Race) Payout(s *discordgo.Session) {
+// Pays out the race and records the result in the same transaction, so after
+// a restart the race has either been settled or still has its bets to refund.
+func (r *Race) Payout(s *discordgo.Session) error {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := r.payout(tx); err != nil {
+			return err
+		}
+		return SaveRaceRecord(tx, r)
+	})
+	if err != nil {
+		// Nothing was paid, the record is still open to be refunded
+		for index := range r.Bets {
+			r.Bets[index].Payout = 0
+		}
+		return err
+	}
+
+	r.awardAchievements(s)
+	return nil
+}
+
+// Award the achievements for winning the race and for losing everything on
+// it, this happens after the race is settled as achievements are cosmetic.
+func (r *Race) awardAchievements(s *discordgo.Session) {
+	for _, snail := range r.Snails {
+		if snail.Level > 0 && r.racePosPosition(snail) == 1 {
+			AwardAchievement(s, r.DB, r.ChannelId, snail.OwnerID, AchievementBigWinner, 1)
+		}
+	}
+
+	for _, bet := range r.Bets {
+		if bet.AllIn && bet.Payout <= 0 {
+			AwardAchievement(s, r.DB, r.ChannelId, bet.UserDiscordId, AchievementJerry, 1)
+		}
+	}
+}
 
-	// Give Snails Base XP
+// Pay out the snails, owners and bets. Any failure is returned so the whole
+// settlement rolls back and the race is left open to be refunded.
+func (r *Race) payout(tx *gorm.DB) error {
+	// Give Snails Base XP, longer races are worth more
+	scale := uint64(r.Length.Multiplier())
+	winners := make([]uint, 0)
 	for _, snail := range r.Snails {
 		if snail.Level == 0 {
 			continue
 		}
 
-		switch r.racePosPosition(snail) {
+		// Fetch the owner fresh, they could have other snails in the race or
+		// have been paid since the snail was loaded.
+		owner, err := GetUserByDiscordID(tx, snail.OwnerID)
+		if err != nil {
+			return fmt.Errorf("getting owner of %s: %w", snail.Name, err)
+		}
+
+		place := r.racePosPosition(snail)
+		if err := snail.updateMood(tx, place); err != nil {
+			return fmt.Errorf("updating the mood of %s: %w", snail.Name, err)
+		}
+
+		xp := uint64(BaseXP)
+		switch place {
 		case 1:
-			snail.AddXP(r.DB, uint64(BaseXP+(WinPos1XP*len(r.Snails))))
-			snail.Owner.AddXP(r.DB, uint64(BaseXP+(WinPos1XP*len(r.Snails))))
-			snail.Owner.AddMoney(r.DB, uint64(BaseMoney*len(r.Snails)))
-			snail.AddRace(r.DB, true)
-			snail.Owner.AddRace(r.DB, true)
+			xp += uint64(WinPos1XP * len(r.Snails))
 		case 2:
-			snail.AddXP(r.DB, uint64(BaseXP+(WinPos2XP*len(r.Snails))))
-			snail.Owner.AddXP(r.DB, uint64(BaseXP+(WinPos2XP*len(r.Snails))))
-			snail.AddRace(r.DB, false)
-			snail.Owner.AddRace(r.DB, false)
+			xp += uint64(WinPos2XP * len(r.Snails))
 		case 3:
-			snail.AddXP(r.DB, uint64(BaseXP+(WinPos3XP*len(r.Snails))))
-			snail.Owner.AddXP(r.DB, uint64(BaseXP+(WinPos3XP*len(r.Snails))))
-			snail.AddRace(r.DB, false)
-			snail.Owner.AddRace(r.DB, false)
-		default:
-			snail.AddXP(r.DB, uint64(BaseXP))
-			snail.Owner.AddXP(r.DB, uint64(BaseXP))
-			snail.AddRace(r.DB, false)
-			snail.Owner.AddRace(r.DB, false)
+			xp += uint64(WinPos3XP * len(r.Snails))
 		}
+
+		if err := snail.AddXP(tx, scale*xp); err != nil {
+			return fmt.Errorf("giving %s xp: %w", snail.Name, err)
+		}
+		if err := owner.AddXP(tx, scale*xp); err != nil {
+			return fmt.Errorf("giving %s xp: %w", owner.DiscordID, err)
+		}
+		if err := snail.AddRace(tx, place == 1); err != nil {
+			return fmt.Errorf("adding race to %s: %w", snail.Name, err)
+		}
+		if err := owner.AddRace(tx, place == 1); err != nil {
+			return fmt.Errorf("adding race to %s: %w", owner.DiscordID, err)
+		}
+
+		if place == 1 {
+			winners = append(winners, snail.ID)
+			prize := int64(scale) * int64(BaseMoney*len(r.Snails))
+			if _, err := Credit(tx, owner.DiscordID, prize, LedgerRacePrize, fmt.Sprintf("Won race %s", r.Id)); err != nil {
+				return fmt.Errorf("paying race prize to %s: %w", owner.DiscordID, err)
+			}
+		}
+	}
+
+	// Hyped winners are focused for their next races
+	if err := settleRaceHypes(tx, r.Id, winners); err != nil {
+		return fmt.Errorf("settling race hypes: %w", err)
 	}
 
 	// Calculate the payout for each bet
-	for _, bet := range r.Bets {
-		if r.racePosPosition(r.Snails[bet.SnailIndex]) == 1 {
-			// Get the user who placed the bet
-			user, err := GetUserByDiscordID(r.DB, bet.UserDiscordId)
-			if err != nil {
-				log.WithField("race", r.Id).WithError(err).Warn("Failed to get user for payout")
-				continue
-			}
+	payouts := r.fixedOddsPayouts()
+	if r.Parimutuel {
+		payouts = r.poolPayouts()
+	}
 
-			// Calculate the payout
-			payout := uint64(float64(bet.Amount) * r.Odds[bet.SnailIndex])
-			user.AddMoney(r.DB, payout)
+	for index, bet := range r.Bets {
+		if payouts[index] <= 0 {
+			continue
+		}
+
+		// Pool bets that are returned because nobody won are refunds
+		kind, memo := LedgerBetWon, fmt.Sprintf("Won %s bet on race %s", bet.Type, r.Id)
+		if !r.betWon(bet) {
+			kind, memo = LedgerBetRefund, fmt.Sprintf("Refunded %s bet on race %s", bet.Type, r.Id)
 		}
+
+		if _, err := Credit(tx, bet.UserDiscordId, int64(payouts[index]), kind, memo); err != nil {
+			return fmt.Errorf("paying out bet for %s: %w", bet.UserDiscordId, err)
+		}
+		r.Bets[index].Payout = payouts[index]
 	}
+	return nil
 }
 
 // Uses the each snails stats, create the odds of the each snail winning. The
@@ -592,20 +1081,27 @@ func (r *Race) Payout(s *discordgo.Session) {
 func (r *Race) generateOdds() {
 	r.Odds = make([]float64, len(r.Snails))
 
-	// Pre-calculate the sum of the speed, and stamina stats to normalize the
-	// stats for each snail later.
-	sum_speed, sum_stamina := 0.0, 0.0
+	// Pre-calculate the sum of the speed, stamina and grip to normalize the
+	// stats for each snail later. Weight shortens a snail's sprint but stops
+	// it sliding back, so speed is scaled by how far the snail sprints and the
+	// grip from weight counts for a little on its own.
+	sum_speed, sum_stamina, sum_grip := 0.0, 0.0, 0.0
 	for _, snail := range r.Snails {
-		sum_speed += snail.Stats.Speed
+		sum_speed += snail.Stats.Speed * simulation.SprintStep(snail.Stats.Weight)
 		sum_stamina += snail.Stats.Stamina
+		sum_grip += 1.0 - simulation.Backslide(snail.Stats.Weight)
 	}
 
 	// Generate for each snail
 	for index, snail := range r.Snails {
 		// Calculate modifier from normalized stats
-		norm_speed := snail.Stats.Speed / sum_speed
+		norm_speed := snail.Stats.Speed * simulation.SprintStep(snail.Stats.Weight) / sum_speed
 		norm_stamina := snail.Stats.Stamina / sum_stamina
-		modifier := 1.0 - (norm_speed + norm_stamina)
+		norm_grip := 0.0
+		if sum_grip > 0 {
+			norm_grip = (1.0 - simulation.Backslide(snail.Stats.Weight)) / sum_grip
+		}
+		modifier := 1.0 - (norm_speed + norm_stamina + 0.25*norm_grip)
 
 		// Check the snail's win history
 		win_rate := 1.0
@@ -625,17 +1121,7 @@ func (r *Race) generateOdds() {
 	}
 }
 
-// Checks if the snail is already in the winners list
-func (r Race) racePosContains(snail *Snail) bool {
-	for _, p := range r.Winners {
-		if p.Snail == snail {
-			return true
-		}
-	}
-	return false
-}
-
-func (r Race) racePosPosition(snail *Snail) int {
+func (r *Race) racePosPosition(snail *Snail) int {
 	for _, p := range r.Winners {
 		if p.Snail == snail {
 			return p.Position
@@ -644,38 +1130,9 @@ func (r Race) racePosPosition(snail *Snail) int {
 	return 0
 }
 
-// When a snail crosses the line, add it to the winners list. If the snail is
-// already in the list, do nothing. If there is already a snail with the same
-// frame, then the snail is tied with the other snail.
-func (r *Race) racePosAdd(snail *Snail, frame int) {
-	if r.racePosContains(snail) {
-		return
-	}
-
-	pos := 1
-	for _, p := range r.Winners {
-		if p.Position >= pos {
-			pos = p.Position + 1
-		}
-
-		// Check for a possible tie
-		if p.Frame == frame {
-			pos = p.Position
-			break
-		}
-	}
-
-	// Add the snail to the winners list
-	r.Winners = append(r.Winners, RaceSnailPos{
-		Position: pos,
-		Snail:    snail,
-		Frame:    frame,
-	})
-}
-
 // Check the race for a tie, it doesn't matter how many are in the tie, just
 // that there is a tie.
-func (r Race) racePosTie() bool {
+func (r *Race) racePosTie() bool {
 	for _, a := range r.Winners {
 		for _, b := range r.Winners {
 			if a.Position == b.Position && a.Snail != b.Snail {
@@ -0,0 +1,228 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// These tests are meant to be run with the race detector, `go test -race`,
+// they hammer the shared race state from many goroutines at once.
+
+// Each test gets its own in memory database, limited to one connection as the
+// shared cache doesn't wait on locks.
+func newTestDB(t *testing.T) *gorm.DB {
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(&User{}, &Snail{}, &RaceRecord{}, &RaceRecordEntrant{}, &RaceRecordBet{}, &LedgerEntry{}, &Trade{}, &TradeSnail{}, &Auction{}, &Raffle{}, &RaffleTicket{}, &ShopListing{}, &AchievementProgress{}, &AdminAction{}, &UserGuild{}, &GuildConfig{}, &Gift{}, &Hype{}, &HypeCheer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestUser(t *testing.T, db *gorm.DB, discordID string, money int64) {
+	if err := db.Create(&User{DiscordID: discordID}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Credit(db, discordID, money, LedgerGift, "test funds"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestRace(t *testing.T, db *gorm.DB) *Race {
+	race := &Race{}
+	race.SetupNewRace("test", "guild", "channel", db, &discordgo.User{Username: "host"}, func() {})
+	race.Message = &discordgo.Message{ID: "message"}
+	race.SetDontFill()
+	if err := openRaceRecord(db, race); err != nil {
+		t.Fatal(err)
+	}
+	return race
+}
+
+func newTestSnail(t *testing.T, db *gorm.DB, id uint, owner string) *Snail {
+	snail := &Snail{Name: fmt.Sprintf("snail-%d", id), OwnerID: owner, Level: 1}
+	snail.ID = id
+	snail.Stats.GenerateStats(StartingSnail)
+	if err := db.Create(snail).Error; err != nil {
+		t.Fatal(err)
+	}
+	return snail
+}
+
+func TestRaceConcurrentJoinAndBet(t *testing.T) {
+	db := newTestDB(t)
+	race := newTestRace(t, db)
+	snails := make(map[uint]*Snail)
+	for user := 0; user < 20; user++ {
+		newTestUser(t, db, fmt.Sprintf("user-%d", user), 1000)
+		for attempt := 0; attempt < 50; attempt++ {
+			id := uint(user*100 + attempt + 1)
+			snails[id] = newTestSnail(t, db, id, fmt.Sprintf("user-%d", user))
+		}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		done   = make(chan struct{})
+		joined atomic.Int64
+		bets   atomic.Int64
+	)
+
+	// Stand in for the race goroutine rendering on every event
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-race.Events:
+				race.renderEdit()
+			}
+		}
+	}()
+
+	// Users joining and betting as fast as they can while the stages change
+	var users sync.WaitGroup
+	for user := 0; user < 20; user++ {
+		users.Add(1)
+		go func(user int) {
+			defer users.Done()
+			owner := fmt.Sprintf("user-%d", user)
+			for attempt := 0; attempt < 50; attempt++ {
+				if race.AddSnail(snails[uint(user*100+attempt+1)]) == nil {
+					joined.Add(1)
+				}
+				if race.PlaceBet(BetWin, []int{attempt % 4}, 1, owner) == nil {
+					bets.Add(1)
+				}
+				race.BetOdds(BetExacta, []int{0, 1})
+				race.GetSnail(attempt % MaxRaceEntrants)
+				race.GetSnails()
+				time.Sleep(100 * time.Microsecond)
+			}
+		}(user)
+	}
+
+	time.Sleep(time.Millisecond)
+	race.closeEntries()
+	time.Sleep(5 * time.Millisecond)
+	race.closeBets()
+	users.Wait()
+
+	close(done)
+	wg.Wait()
+
+	if got := int64(len(race.Snails)); got != joined.Load() {
+		t.Errorf("race has %d snails but %d joins succeeded", got, joined.Load())
+	}
+	if len(race.Snails) > MaxRaceEntrants {
+		t.Errorf("race has %d snails, more than the max of %d", len(race.Snails), MaxRaceEntrants)
+	}
+	if got := int64(len(race.Bets)); got != bets.Load() {
+		t.Errorf("race has %d bets but %d bets succeeded", got, bets.Load())
+	}
+	if len(race.Odds) != len(race.Snails) {
+		t.Errorf("race has %d odds for %d snails", len(race.Odds), len(race.Snails))
+	}
+	if race.AddSnail(newTestSnail(t, db, 9999, "late")) != ErrRaceClosed {
+		t.Error("joined a race that is already running")
+	}
+	if race.PlaceBet(BetWin, []int{0}, 1, "late") != ErrBetsClosed {
+		t.Error("bet on a race that is already running")
+	}
+
+	// Every accepted bet was recorded and paid for, nothing else was
+	var recorded, spent int64
+	db.Model(&RaceRecordBet{}).Where("race_record_id = ?", race.recordID).Count(&recorded)
+	db.Model(&LedgerEntry{}).Where("kind = ?", LedgerBetPlaced).Select("-sum(amount)").Scan(&spent)
+	if recorded != bets.Load() {
+		t.Errorf("recorded %d bets but %d bets succeeded", recorded, bets.Load())
+	}
+	if spent != bets.Load() {
+		t.Errorf("users spent %dg on %d bets of 1g", spent, bets.Load())
+	}
+}
+
+func TestStateConcurrentRaces(t *testing.T) {
+	state := NewState(newTestDB(t))
+
+	var wg sync.WaitGroup
+	for host := 0; host < 20; host++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			race, err := state.NewRace(nil, "guild", "channel", &discordgo.User{Username: "host"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if found, ok := state.GetRace("guild", "channel", race.Id); !ok || found != race {
+				t.Errorf("race %s not found after hosting", race.Id)
+			}
+			race.EndRace()
+			if _, ok := state.GetRace("guild", "channel", race.Id); ok {
+				t.Errorf("race %s still found after ending", race.Id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	state.Shutdown(time.Second)
+	if _, err := state.NewRace(nil, "guild", "channel", &discordgo.User{Username: "host"}); err != ErrShuttingDown {
+		t.Errorf("hosted a race while shutting down, got %v", err)
+	}
+}
+
+func TestJoinChecksSnailIsAvailable(t *testing.T) {
+	db := newTestDB(t)
+	state := NewState(db)
+	race, err := state.NewRace(nil, "guild", "channel", &discordgo.User{Username: "host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	race.SetDontFill()
+
+	// The snails were looked up before they were offered up or given away
+	escrowed := newTestSnail(t, db, 1, "owner")
+	given := newTestSnail(t, db, 2, "owner")
+	db.Model(&Snail{}).Where("id = ?", escrowed.ID).Update("escrow", "trade:test")
+	db.Model(&Snail{}).Where("id = ?", given.ID).Update("owner_id", "other")
+	for _, snail := range []*Snail{escrowed, given} {
+		if err := race.AddSnail(snail); err != ErrSnailInEscrow {
+			t.Errorf("snail %d joined after it was taken, got %v", snail.ID, err)
+		}
+	}
+
+	// Racing snails can't be escrowed, idle ones can
+	racing := newTestSnail(t, db, 3, "owner")
+	if err := race.AddSnail(racing); err != nil {
+		t.Fatal(err)
+	}
+	ran := false
+	if err := state.WhileIdle(func() error { ran = true; return nil }, racing.ID); err != ErrSnailRacing || ran {
+		t.Errorf("escrowed a racing snail, got %v", err)
+	}
+	if err := state.WhileIdle(func() error { ran = true; return nil }, escrowed.ID); err != nil || !ran {
+		t.Errorf("couldn't escrow an idle snail, got %v", err)
+	}
+}
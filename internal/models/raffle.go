@@ -0,0 +1,297 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type RaffleStatus string
+
+const (
+	RaffleStatusOpen  RaffleStatus = "open"
+	RaffleStatusDrawn RaffleStatus = "drawn"
+	RaffleStatusVoid  RaffleStatus = "void" // Nobody bought a ticket
+
+	// Raffle Constants
+	RaffleTicketPrice = 10
+	MaxRaffleTickets  = 100 // Most tickets that can be bought at once
+	RafflePrizeLevel  = ExpertSnail
+
+	// The channel the draws are announced in, e.g. `RAFFLE_CHANNEL=1234`
+	RaffleChannelEnv = "RAFFLE_CHANNEL"
+
+	// The hours between draws, e.g. `RAFFLE_INTERVAL=24`
+	RaffleIntervalEnv     = "RAFFLE_INTERVAL"
+	DefaultRaffleInterval = 24 * time.Hour
+)
+
+var ErrNoRaffleTickets = fmt.Errorf("raffle has no tickets")
+
+// Raffle is a single draw. Every ticket bought before the draw has an equal
+// chance of winning, so users with more tickets are more likely to win. The
+// seed used to draw the winner is kept so the draw can be checked later.
+type Raffle struct {
+	gorm.Model
+
+	Status RaffleStatus `gorm:"index"`
+	DrawAt time.Time
+
+	Seed          int64
+	WinningNumber int64  // The winning ticket number, counting from 0
+	WinnerID      string `gorm:"index"`
+	SnailID       uint
+	Snail         Snail
+}
+
+// RaffleTicket is a purchase of one or more tickets in a raffle, the tickets
+// are numbered in the order they were bought.
+type RaffleTicket struct {
+	gorm.Model
+
+	RaffleID      uint   `gorm:"index"`
+	UserDiscordID string `gorm:"index"`
+	Count         int64
+}
+
+// The time between draws
+func raffleInterval() time.Duration {
+	value := os.Getenv(RaffleIntervalEnv)
+	if value == "" {
+		return DefaultRaffleInterval
+	}
+
+	hours, err := strconv.ParseFloat(value, 64)
+	if err != nil || hours <= 0 {
+		log.WithField("env", RaffleIntervalEnv).Warnf("Invalid raffle interval %q, using the default", value)
+		return DefaultRaffleInterval
+	}
+	return time.Duration(hours * float64(time.Hour))
+}
+
+// Get the raffle that is taking tickets, starting a new one if there isn't
+// one already.
+func GetOpenRaffle(db *gorm.DB) (*Raffle, error) {
+	raffle := &Raffle{}
+	result := db.Where("status = ?", RaffleStatusOpen).Order("id asc").First(raffle)
+	if result.Error == gorm.ErrRecordNotFound {
+		raffle = &Raffle{Status: RaffleStatusOpen, DrawAt: time.Now().Add(raffleInterval())}
+		return raffle, db.Omit("Snail").Create(raffle).Error
+	}
+	return raffle, result.Error
+}
+
+func GetRaffleTickets(db *gorm.DB, raffleID uint) ([]RaffleTicket, error) {
+	tickets := []RaffleTicket{}
+	result := db.Where("raffle_id = ?", raffleID).Order("id asc").Find(&tickets)
+	return tickets, result.Error
+}
+
+// How many tickets are in the raffle, and how many of them are the user's
+func CountRaffleTickets(db *gorm.DB, raffleID uint, discordID string) (int64, int64, error) {
+	tickets, err := GetRaffleTickets(db, raffleID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	total, owned := int64(0), int64(0)
+	for _, ticket := range tickets {
+		total += ticket.Count
+		if ticket.UserDiscordID == discordID {
+			owned += ticket.Count
+		}
+	}
+	return total, owned, nil
+}
+
+// BuyRaffleTickets buys the user some tickets in the open raffle
+func BuyRaffleTickets(db *gorm.DB, discordID string, count int64) (*Raffle, error) {
+	log.Debugf("BuyRaffleTickets(id: %s, count: %d)", discordID, count)
+
+	if count <= 0 || count > MaxRaffleTickets {
+		return nil, ErrInvalidAmount
+	}
+
+	var raffle *Raffle
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if raffle, err = GetOpenRaffle(tx); err != nil {
+			return err
+		}
+
+		// Make sure the raffle isn't being drawn while the tickets are bought
+		result := tx.Model(&Raffle{}).Where("id = ? AND status = ?", raffle.ID, RaffleStatusOpen).Update("updated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("raffle %d has already been drawn", raffle.ID)
+		}
+
+		memo := fmt.Sprintf("%d tickets in raffle #%d", count, raffle.ID)
+		if _, err := Debit(tx, discordID, count*RaffleTicketPrice, LedgerRaffle, memo); err != nil {
+			return err
+		}
+		return tx.Create(&RaffleTicket{RaffleID: raffle.ID, UserDiscordID: discordID, Count: count}).Error
+	})
+	return raffle, err
+}
+
+// Pick the winning ticket with the seed. The same tickets and seed always pick
+// the same winner, which is how a draw can be checked.
+func PickRaffleTicket(tickets []RaffleTicket, seed int64) (*RaffleTicket, int64) {
+	total := int64(0)
+	for _, ticket := range tickets {
+		total += ticket.Count
+	}
+	if total == 0 {
+		return nil, 0
+	}
+
+	number := rand.New(rand.NewSource(seed)).Int63n(total)
+	remaining := number
+	for i := range tickets {
+		if remaining < tickets[i].Count {
+			return &tickets[i], number
+		}
+		remaining -= tickets[i].Count
+	}
+	return nil, 0
+}
+
+// DrawRaffle draws the winner of the raffle and gives them a prize snail. If
+// nobody bought a ticket the raffle is void and ErrNoRaffleTickets returned.
+func DrawRaffle(db *gorm.DB, raffle *Raffle) error {
+	log.Debugf("DrawRaffle(raffle: %d)", raffle.ID)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		tickets, err := GetRaffleTickets(tx, raffle.ID)
+		if err != nil {
+			return err
+		}
+
+		seed := time.Now().UnixNano()
+		ticket, number := PickRaffleTicket(tickets, seed)
+		if ticket == nil {
+			if err := raffle.close(tx, map[string]interface{}{"status": RaffleStatusVoid}); err != nil {
+				return err
+			}
+			raffle.Status = RaffleStatusVoid
+			return nil
+		}
+
+		winner, err := GetUserByDiscordID(tx, ticket.UserDiscordID)
+		if err != nil {
+			return err
+		}
+		snail, err := CreateSnail(tx, *winner, RafflePrizeLevel)
+		if err != nil {
+			return err
+		}
+
+		err = raffle.close(tx, map[string]interface{}{
+			"status":         RaffleStatusDrawn,
+			"seed":           seed,
+			"winning_number": number,
+			"winner_id":      winner.DiscordID,
+			"snail_id":       snail.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		raffle.Status, raffle.Seed, raffle.WinningNumber = RaffleStatusDrawn, seed, number
+		raffle.WinnerID, raffle.SnailID, raffle.Snail = winner.DiscordID, snail.ID, *snail
+		return nil
+	})
+	if err == nil && raffle.Status == RaffleStatusVoid {
+		return ErrNoRaffleTickets
+	}
+	return err
+}
+
+// Move the raffle on from open, this only works once so it can't be drawn
+// twice.
+func (r *Raffle) close(tx *gorm.DB, updates map[string]interface{}) error {
+	result := tx.Model(&Raffle{}).Where("id = ? AND status = ?", r.ID, RaffleStatusOpen).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("raffle %d has already been drawn", r.ID)
+	}
+	return nil
+}
+
+// StartRaffles runs the raffles in the background
+func StartRaffles(s *discordgo.Session, db *gorm.DB) {
+	goBackground(func(ctx context.Context) { RunRaffles(ctx, s, db) })
+}
+
+// RunRaffles draws the open raffle when it is due and starts the next one,
+// until it is stopped. The draw time is kept in the database, so a raffle that
+// was due while the bot was down is drawn as soon as it starts.
+func RunRaffles(ctx context.Context, s *discordgo.Session, db *gorm.DB) {
+	for ctx.Err() == nil {
+		raffle, err := GetOpenRaffle(db)
+		if err != nil {
+			log.WithError(err).Error("Failed to get the open raffle")
+			sleepBackground(ctx, time.Minute)
+			continue
+		}
+
+		if !sleepBackground(ctx, time.Until(raffle.DrawAt)) {
+			return
+		}
+
+		switch err := DrawRaffle(db, raffle); err {
+		case nil:
+			log.WithField("raffle", raffle.ID).Infof("Raffle won by %s with seed %d", raffle.WinnerID, raffle.Seed)
+			raffle.Announce(s)
+			AwardAchievement(s, db, os.Getenv(RaffleChannelEnv), raffle.WinnerID, AchievementTicketMaster, 1)
+			AwardSnailCount(s, db, os.Getenv(RaffleChannelEnv), raffle.WinnerID)
+		case ErrNoRaffleTickets:
+			log.WithField("raffle", raffle.ID).Info("Raffle had no tickets")
+		default:
+			log.WithField("raffle", raffle.ID).WithError(err).Error("Failed to draw raffle")
+			sleepBackground(ctx, time.Minute)
+		}
+	}
+}
+
+// Announce the winner of the raffle in the raffle channel
+func (r *Raffle) Announce(s *discordgo.Session) {
+	channelId := os.Getenv(RaffleChannelEnv)
+	if channelId == "" {
+		log.WithField("env", RaffleChannelEnv).Warn("No raffle channel set, not announcing the winner")
+		return
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelId, &discordgo.MessageSend{
+		Content: Mention(r.WinnerID),
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title: fmt.Sprintf("Raffle #%d Winner", r.ID),
+				Color: 0x2ecc71,
+				Description: fmt.Sprintf(
+					"Congratulations %s, ticket #%d won the raffle! Your prize is **%s**:\n```\n%s```",
+					Mention(r.WinnerID), r.WinningNumber+1, r.Snail.Name, r.Snail.Stats.RenderStatBlock(),
+				),
+				Footer: &discordgo.MessageEmbedFooter{
+					Text: fmt.Sprintf("Drawn with seed %d", r.Seed),
+				},
+			},
+		},
+	})
+	if err != nil {
+		log.WithField("raffle", r.ID).WithError(err).Warn("Failed to announce raffle winner")
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDrawRaffleCanBeChecked(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "few", 100)
+	newTestUser(t, db, "many", 100)
+
+	raffle, err := BuyRaffleTickets(db, "few", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuyRaffleTickets(db, "many", 9); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := BuyRaffleTickets(db, "many", 10); err != ErrInsufficientFunds {
+		t.Errorf("bought tickets without the money, got %v", err)
+	}
+
+	if err := DrawRaffle(db, raffle); err != nil {
+		t.Fatal(err)
+	}
+	if err := DrawRaffle(db, raffle); err == nil {
+		t.Error("drew the same raffle twice")
+	}
+
+	// Anyone with the tickets and the seed gets the same winner
+	stored := &Raffle{}
+	db.First(stored, raffle.ID)
+	tickets, _ := GetRaffleTickets(db, raffle.ID)
+	ticket, number := PickRaffleTicket(tickets, stored.Seed)
+	if ticket.UserDiscordID != stored.WinnerID || number != stored.WinningNumber {
+		t.Errorf("seed %d picks %s with ticket %d, but %s won with ticket %d", stored.Seed, ticket.UserDiscordID, number, stored.WinnerID, stored.WinningNumber)
+	}
+
+	prize := &Snail{}
+	db.First(prize, stored.SnailID)
+	if prize.OwnerID != stored.WinnerID {
+		t.Errorf("prize snail is owned by %q, not the winner %q", prize.OwnerID, stored.WinnerID)
+	}
+	// The winner is shown the prize's stats when it is announced
+	if prize.Stats.Speed < MinStat || prize.Stats.Stamina < MinStat || prize.Stats.Recovery < MinStat {
+		t.Errorf("prize snail has no stats, %+v", prize.Stats)
+	}
+	if block := prize.Stats.RenderStatBlock(); !strings.Contains(block, fmt.Sprintf("%.02f", prize.Stats.Speed)) {
+		t.Errorf("prize stat block doesn't show its speed:\n%s", block)
+	}
+
+	// The next raffle has no tickets yet
+	next, _ := GetOpenRaffle(db)
+	if err := DrawRaffle(db, next); err != ErrNoRaffleTickets {
+		t.Errorf("drew a raffle with no tickets, got %v", err)
+	}
+}
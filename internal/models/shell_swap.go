@@ -0,0 +1,57 @@
+package models
+
+import (
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SwapShells swaps the shells, and so the weight, of two of the owner's
+// snails. The surgery leaves both snails sad for their next MoodLockRaces
+// races. Neither snail can be in escrow, checking they aren't racing is left
+// to the caller as it needs the race state.
+func SwapShells(db *gorm.DB, owner User, a *Snail, b *Snail) error {
+	log.Debugf("SwapShells(owner: %s, a: %d, b: %d)", owner.DiscordID, a.ID, b.ID)
+
+	if a.ID == b.ID {
+		return ErrSameSnail
+	}
+	if a.OwnerID != owner.DiscordID || b.OwnerID != owner.DiscordID {
+		return ErrInvalidSnail
+	}
+
+	weights := map[uint]float64{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Swap using the current weights in case either changed since the
+		// snails were looked up
+		current := []Snail{}
+		result := tx.Where("id IN ? AND owner_id = ? AND escrow = ''", []uint{a.ID, b.ID}, owner.DiscordID).Find(&current)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(current) != 2 {
+			return ErrSnailInEscrow
+		}
+		for _, snail := range current {
+			weights[snail.ID] = snail.Stats.Weight
+		}
+
+		for _, pair := range [][2]uint{{a.ID, b.ID}, {b.ID, a.ID}} {
+			if err := tx.Model(&Snail{}).Where("id = ?", pair[0]).Update("weight", weights[pair[1]]).Error; err != nil {
+				return err
+			}
+			if err := LockMood(tx, pair[0], MoodSad, MoodLockRaces); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	a.Stats.Weight, b.Stats.Weight = weights[b.ID], weights[a.ID]
+	a.MoodLock, a.MoodRaces = MoodSad, MoodLockRaces
+	b.MoodLock, b.MoodRaces = MoodSad, MoodLockRaces
+	return nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import "testing"
+
+func TestSwapShells(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	owner, _ := GetUserByDiscordID(db, "owner")
+	snails := []Snail{newTestOwnedSnail(t, db, "owner"), newTestOwnedSnail(t, db, "owner")}
+	db.Model(&snails[0]).Update("weight", 4.0)
+	db.Model(&snails[1]).Update("weight", 16.0)
+
+	if err := SwapShells(db, *owner, &snails[0], &snails[0]); err != ErrSameSnail {
+		t.Errorf("swapped a snail's shell with itself, got %v", err)
+	}
+	if err := SwapShells(db, *owner, &snails[0], &snails[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for index, weight := range []float64{16, 4} {
+		swapped := Snail{}
+		db.First(&swapped, snails[index].ID)
+		if swapped.Stats.Weight != weight {
+			t.Errorf("snail %d has weight %.1f after the swap", index, swapped.Stats.Weight)
+		}
+		if swapped.MoodLock != MoodSad || swapped.MoodRaces != MoodLockRaces {
+			t.Errorf("snail %d has mood %d for %d races after the swap", index, swapped.MoodLock, swapped.MoodRaces)
+		}
+	}
+
+	// Snails held in escrow can't go under the knife
+	db.Model(&snails[1]).Update("escrow", "trade:test")
+	if err := SwapShells(db, *owner, &snails[0], &snails[1]); err != ErrSnailInEscrow {
+		t.Errorf("swapped the shell of a held snail, got %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// Shop Constants
+	RandomSnailPrice    = 100
+	SnailSellPrice      = 75
+	ShopRefreshInterval = 6 * time.Hour
+)
+
+// The tiers of snail in the shop stock and what they cost
+var ShopTiers = []struct {
+	Level SnailStatLevel
+	Price int64
+}{
+	{AmateurSnail, 250},
+	{ProfessionalSnail, 600},
+	{ExpertSnail, 1500},
+}
+
+var (
+	ErrListingNotFound = fmt.Errorf("listing not found")
+	ErrListingSold     = fmt.Errorf("listing has already been sold")
+)
+
+// ShopListing is a pre-rolled snail in the shop stock. The stock is replaced
+// every refresh interval, and each listing can only be bought once.
+type ShopListing struct {
+	gorm.Model
+
+	// The refresh the listing belongs to, counting intervals since the epoch
+	Rotation int64          `gorm:"uniqueIndex:idx_shop_slot"`
+	Tier     SnailStatLevel `gorm:"uniqueIndex:idx_shop_slot"`
+	Price    int64
+
+	Name   string
+	Stats  SnailStats `gorm:"embedded"`
+	Genome Genome
+
+	BuyerID string `gorm:"index"`
+}
+
+// A short human description of the level of snail
+func (level SnailStatLevel) Title() string {
+	switch level {
+	case StartingSnail:
+		return "Starting"
+	case AmateurSnail:
+		return "Amateur"
+	case ProfessionalSnail:
+		return "Professional"
+	case ExpertSnail:
+		return "Expert"
+	}
+	return "Random"
+}
+
+func (l ShopListing) Sold() bool {
+	return l.BuyerID != ""
+}
+
+// The current rotation and when it is replaced
+func shopRotation(now time.Time) (int64, time.Time) {
+	interval := int64(ShopRefreshInterval / time.Second)
+	rotation := now.Unix() / interval
+	return rotation, time.Unix((rotation+1)*interval, 0)
+}
+
+// GetShopStock gets the listings in the current rotation, rolling them the
+// first time they are asked for. The stock is rolled once per rotation even if
+// it is asked for at the same time, as the slots are unique.
+func GetShopStock(db *gorm.DB) ([]ShopListing, time.Time, error) {
+	rotation, refreshAt := shopRotation(time.Now())
+
+	listings := make([]ShopListing, 0)
+	result := db.Where("rotation = ?", rotation).Order("tier asc").Find(&listings)
+	if result.Error != nil || len(listings) == len(ShopTiers) {
+		return listings, refreshAt, result.Error
+	}
+
+	listings = make([]ShopListing, 0)
+	for _, tier := range ShopTiers {
+		stats := SnailStats{}
+		stats.GenerateStats(tier.Level)
+		genome := RandomGenome()
+		genome.expressStats(&stats)
+
+		listings = append(listings, ShopListing{
+			Rotation: rotation,
+			Tier:     tier.Level,
+			Price:    tier.Price,
+			Name:     generateSnailName(),
+			Stats:    stats,
+			Genome:   genome,
+		})
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&listings).Error; err != nil {
+		return nil, refreshAt, err
+	}
+
+	listings = make([]ShopListing, 0)
+	result = db.Where("rotation = ?", rotation).Order("tier asc").Find(&listings)
+	return listings, refreshAt, result.Error
+}
+
+func GetShopListing(db *gorm.DB, id string) (*ShopListing, error) {
+	listing := &ShopListing{}
+	result := db.Where("id = ?", id).First(listing)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrListingNotFound
+	}
+	return listing, result.Error
+}
+
+// BuyRandomSnail buys the user a snail with random stats
+func BuyRandomSnail(db *gorm.DB, buyer User) (*Snail, error) {
+	log.Debugf("BuyRandomSnail(buyer: %s)", buyer.DiscordID)
+
+	var snail *Snail
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := Debit(tx, buyer.DiscordID, RandomSnailPrice, LedgerPurchase, "Random snail"); err != nil {
+			return err
+		}
+
+		var err error
+		snail, err = CreateSnail(tx, buyer, RandomSnail)
+		if err != nil {
+			return err
+		}
+		return ensureActiveSnail(tx, buyer.DiscordID)
+	})
+	return snail, err
+}
+
+// BuyShopListing buys the listed snail for the user, as long as it is still in
+// stock and nobody else has bought it.
+func BuyShopListing(db *gorm.DB, buyer User, listing *ShopListing) (*Snail, error) {
+	log.Debugf("BuyShopListing(buyer: %s, listing: %d)", buyer.DiscordID, listing.ID)
+
+	rotation, _ := shopRotation(time.Now())
+	if listing.Rotation != rotation {
+		return nil, ErrListingNotFound
+	}
+
+	snail := &Snail{
+		Owner:  buyer,
+		Level:  1,
+		Name:   listing.Name,
+		Stats:  listing.Stats,
+		Genome: listing.Genome,
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ShopListing{}).
+			Where("id = ? AND buyer_id = ''", listing.ID).
+			Update("buyer_id", buyer.DiscordID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrListingSold
+		}
+
+		memo := fmt.Sprintf("%s snail %s", listing.Tier.Title(), listing.Name)
+		if _, err := Debit(tx, buyer.DiscordID, listing.Price, LedgerPurchase, memo); err != nil {
+			return err
+		}
+		if err := tx.Create(snail).Error; err != nil {
+			return err
+		}
+		return ensureActiveSnail(tx, buyer.DiscordID)
+	})
+	return snail, err
+}
+
+// SellSnail sells the owner's snail back to the shop. Snails in escrow and the
+// owner's last snail can't be sold, checking the snail isn't racing is left to
+// the caller as it needs the race state.
+func SellSnail(db *gorm.DB, owner User, snail Snail) error {
+	log.Debugf("SellSnail(owner: %s, snail: %d)", owner.DiscordID, snail.ID)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		keeps, err := keepsASnail(tx, owner.DiscordID, 1, 0)
+		if err != nil {
+			return err
+		}
+		if !keeps {
+			return ErrLastSnail
+		}
+
+		result := tx.Where("id = ? AND owner_id = ? AND escrow = ''", snail.ID, owner.DiscordID).Delete(&Snail{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSnailInEscrow
+		}
+
+		if _, err := Credit(tx, owner.DiscordID, SnailSellPrice, LedgerSale, fmt.Sprintf("Sold %s", snail.Name)); err != nil {
+			return err
+		}
+		return ensureActiveSnail(tx, owner.DiscordID)
+	})
+}
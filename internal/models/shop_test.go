@@ -0,0 +1,60 @@
+package models
+
+import "testing"
+
+func TestShopListingsSellOnce(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "first", 2000)
+	newTestUser(t, db, "second", 2000)
+	first, _ := GetUserByDiscordID(db, "first")
+	second, _ := GetUserByDiscordID(db, "second")
+
+	stock, _, err := GetShopStock(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _, _ := GetShopStock(db)
+	if len(stock) != len(ShopTiers) || len(again) != len(ShopTiers) || stock[0].ID != again[0].ID {
+		t.Fatalf("stock was rolled again in the same rotation, got %d then %d listings", len(stock), len(again))
+	}
+
+	snail, err := BuyShopListing(db, *first, &stock[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snail.Name != stock[0].Name || snail.Stats != stock[0].Stats {
+		t.Errorf("bought %s but got %s", stock[0].Name, snail.Name)
+	}
+	if _, err := BuyShopListing(db, *second, &stock[0]); err != ErrListingSold {
+		t.Errorf("bought a listing twice, got %v", err)
+	}
+}
+
+func TestSellSnailKeepsOne(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "owner", 1)
+	owner, _ := GetUserByDiscordID(db, "owner")
+	snails := []Snail{newTestOwnedSnail(t, db, "owner"), newTestOwnedSnail(t, db, "owner")}
+
+	db.Model(&snails[0]).Update("escrow", "trade:test")
+	if err := SellSnail(db, *owner, snails[0]); err != ErrSnailInEscrow {
+		t.Errorf("sold a snail in escrow, got %v", err)
+	}
+	db.Model(&snails[0]).Update("escrow", "")
+
+	if err := SellSnail(db, *owner, snails[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := SellSnail(db, *owner, snails[1]); err != ErrLastSnail {
+		t.Errorf("sold the last snail, got %v", err)
+	}
+
+	owner, _ = GetUserByDiscordID(db, "owner")
+	if owner.Money != 11+SnailSellPrice {
+		t.Errorf("owner has %dg after selling a snail", owner.Money)
+	}
+	active, err := GetActiveSnail(db, *owner)
+	if err != nil || active.ID != snails[1].ID {
+		t.Errorf("the snail left isn't the racer, got %v", err)
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"math"
 	"math/rand"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/lcox74/snailrace/internal/simulation"
 	"gorm.io/gorm"
 
 	log "github.com/sirupsen/logrus"
@@ -16,11 +19,9 @@ type SnailMood int
 
 const (
 	MoodSad     SnailMood = -1
+	MoodNeutral SnailMood = 0
 	MoodHappy   SnailMood = 1
-	MoodFocused SnailMood = 0
-
-	MaxSnailStep  float64 = 5.0
-	MaxRaceLength int     = 100
+	MoodFocused SnailMood = 2 // Only from being hyped up, see LockMood
 )
 
 type Snail struct {
@@ -36,49 +37,52 @@ type Snail struct {
 	Races uint64 `json:"races" gorm:"default:0"`
 	Wins  uint64 `json:"wins" gorm:"default:0"`
 
-	Mood  float64    `json:"mood" gorm:"default:0"`
-	Stats SnailStats `json:"stats" gorm:"embedded"`
-
-	racePosition   float64 `json:"-" gorm:"-"`
-	currentStamina float64 `json:"-" gorm:"-"`
+	// Mood goes from -1 (sad) to 1 (happy) and drifts back to neutral from
+	// when it was last changed. A locked mood overrides it for MoodRaces more
+	// races, see mood.go
+	Mood          float64   `json:"mood" gorm:"default:0"`
+	MoodUpdatedAt time.Time `json:"-"`
+	MoodLock      SnailMood `json:"-" gorm:"default:0"`
+	MoodRaces     uint64    `json:"-" gorm:"default:0"`
+
+	Stats  SnailStats `json:"stats" gorm:"embedded"`
+	Genome Genome     `json:"-"`
+
+	// Snails held for a trade or auction are in escrow, they can't be raced,
+	// sold or offered anywhere else until they are released.
+	Escrow string `json:"-" gorm:"index;not null;default:''"`
+
+	// Bred snails keep track of their parents and who bred them, snails that
+	// weren't bred have no parents.
+	SireID    uint   `json:"sire_id" gorm:"index"`
+	DamID     uint   `json:"dam_id" gorm:"index"`
+	BreederID string `json:"breeder_id" gorm:"index"`
+
+	racePosition float64 `json:"-" gorm:"-"`
+	moodBias     float64 `json:"-" gorm:"-"` // The mood the snail races with
 }
 
-func (s *Snail) NewRace() {
-	s.racePosition = 0
-	s.currentStamina = s.Stats.Stamina
+// Entrant converts the snail into what the race simulation needs to know
+func (s Snail) Entrant() simulation.Entrant {
+	return simulation.Entrant{
+		Speed:    s.Stats.Speed,
+		Stamina:  s.Stats.Stamina,
+		Recovery: s.Stats.Recovery,
+		Weight:   s.Stats.Weight,
+		Mood:     s.moodBias,
+	}
 }
 
-// Step calculates the next step for the snail, based on the snail's stats and
-// mood. This is still in testing stages and will probably be changed depending
-// on how the game feels.
-func (s *Snail) Step() {
-	// Generate Random Bias
-	bias := generateMoodBias(s.Mood)
-
-	// Calculate max next step
-	maxStepPotential := (s.Stats.Speed / 20.0 * MaxSnailStep) // ?
-
-	if s.currentStamina > 0.0 {
-		if bias >= (1.0 - maxStepPotential) {
-			s.racePosition += MaxSnailStep
-			s.currentStamina -= rand.Float64() * 2.0
-		} else {
-			s.racePosition += float64(rand.Intn(int(MaxSnailStep)))
-			s.currentStamina -= rand.Float64()
-		}
-
-		s.racePosition -= rand.Float64()
-	} else {
-		s.currentStamina += s.Stats.Recovery / 10.0
+// How far the snail is through its current level, out of 100
+func (s Snail) LevelProgress() float64 {
+	if s.Level == 0 {
+		return 0
 	}
-
-	// Make sure the snail doesn't go out of bounds
-	s.racePosition = math.Min(s.racePosition, float64(MaxRaceLength))
-	s.currentStamina = math.Max(0.0, s.currentStamina)
+	return float64(s.Exp * 100 / (s.Level * 100))
 }
 
-func (s Snail) renderPosition() string {
-	trail := int((s.racePosition/float64(MaxRaceLength))*20.0) - 1
+func (s Snail) renderPosition(length RaceLength) string {
+	trail := int((s.racePosition/float64(length))*20.0) - 1
 	line := strings.Repeat(".", int(math.Max(0.0, float64(trail))))
 	line += "🐌"
 
@@ -87,7 +91,7 @@ func (s Snail) renderPosition() string {
 
 func (s Snail) renderName(codeBlock bool) string {
 	if s.Level > 0 && !codeBlock {
-		return fmt.Sprintf("%s (<@%s>)", s.Name, s.OwnerID)
+		return fmt.Sprintf("%s (%s)", s.Name, Mention(s.OwnerID))
 	}
 	return s.Name
 }
@@ -96,10 +100,12 @@ func CreateSnail(db *gorm.DB, owner User, levelType SnailStatLevel) (*Snail, err
 	log.Debugf("CreateSnail(owner: %s, levelType: %v)", owner.DiscordID, levelType)
 
 	snail := &Snail{
-		Owner: owner,
-		Level: 1,
+		Owner:  owner,
+		Level:  1,
+		Genome: RandomGenome(),
 	}
 	snail.Stats.GenerateStats(levelType)
+	snail.Genome.expressStats(&snail.Stats)
 	snail.Name = generateSnailName()
 
 	result := db.Create(snail)
@@ -109,8 +115,9 @@ func CreateSnail(db *gorm.DB, owner User, levelType SnailStatLevel) (*Snail, err
 func CreateDummySnail(levelType SnailStatLevel) *Snail {
 	log.Debugf("CreateDummySnail(levelType: %v)", levelType)
 
-	snail := &Snail{Level: 0}
+	snail := &Snail{Level: 0, Genome: RandomGenome()}
 	snail.Stats.GenerateStats(levelType)
+	snail.Genome.expressStats(&snail.Stats)
 	snail.Name = generateSnailName()
 
 	return snail
@@ -124,6 +131,23 @@ func GetAllSnails(db *gorm.DB, owner User) ([]Snail, error) {
 	return snails, result.Error
 }
 
+// FindOwnedSnail looks up one of the owner's snails, the query can either be
+// the snail's id (which is what the autocomplete options use) or its name.
+func FindOwnedSnail(db *gorm.DB, owner User, query string) (*Snail, error) {
+	log.Debugf("FindOwnedSnail(owner: %s, query: %s)", owner.DiscordID, query)
+
+	snail := &Snail{}
+	if id, err := strconv.ParseUint(query, 10, 64); err == nil {
+		result := db.Where("owner_id = ? AND id = ?", owner.DiscordID, id).Preload("Owner").First(snail)
+		if result.Error == nil {
+			return snail, nil
+		}
+	}
+
+	result := db.Where("owner_id = ? AND name = ?", owner.DiscordID, query).Preload("Owner").First(snail)
+	return snail, result.Error
+}
+
 func GetActiveSnail(db *gorm.DB, owner User) (*Snail, error) {
 	log.Debugf("GetActiveSnail(owner: %s)", owner.DiscordID)
 
@@ -144,7 +168,7 @@ func SetActiveSnail(db *gorm.DB, owner User, snail Snail) error {
 }
 
 func (snail *Snail) AddXP(db *gorm.DB, amount uint64) error {
-	log.Debugf("GetActiveSnail(snail: %s, amount: %d)", snail.Name, amount)
+	log.Debugf("AddXP(snail: %s, amount: %d)", snail.Name, amount)
 
 	snail.Exp += amount
 	if snail.Exp >= snail.Level*100 {
@@ -152,7 +176,9 @@ func (snail *Snail) AddXP(db *gorm.DB, amount uint64) error {
 		snail.Level++
 	}
 
-	result := db.Save(snail)
+	// Only save the columns we changed, the snail could have been traded
+	// since it was loaded
+	result := db.Model(snail).Select("Exp", "Level").Updates(snail)
 	return result.Error
 }
 
@@ -164,7 +190,7 @@ func (snail *Snail) AddRace(db *gorm.DB, win bool) error {
 		snail.Wins++
 	}
 
-	result := db.Save(snail)
+	result := db.Model(snail).Select("Races", "Wins").Updates(snail)
 	return result.Error
 }
 
@@ -185,7 +211,3 @@ func generateSnailName() string {
 
 	return adjectives[rand.Intn(len(adjectives))] + "-" + nouns[rand.Intn(len(nouns))]
 }
-
-func generateMoodBias(mood float64) float64 {
-	return rand.Float64() + mood
-}
@@ -4,12 +4,21 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
 )
 
 type SnailStats struct {
 	Speed    float64 `json:"speed"`
 	Stamina  float64 `json:"stamina"`
 	Recovery float64 `json:"recovery"`
+
+	// Weight trades some speed for steadiness rather than being better the
+	// higher it is, so every snail gets a random weight whatever its level.
+	// Snails from before weight have none until they are backfilled.
+	Weight float64 `json:"weight"`
 }
 
 type SnailStatLevel uint8
@@ -24,10 +33,11 @@ const (
 
 func (s SnailStats) RenderStatBlock() string {
 	return fmt.Sprintf(
-		"%-9s%s %.02f\n%-9s%s %.02f\n%-9s%s %.02f\n",
+		"%-9s%s %.02f\n%-9s%s %.02f\n%-9s%s %.02f\n%-9s%s %.02f\n",
 		"Speed", renderStat(s.Speed), s.Speed,
 		"Stamina", renderStat(s.Stamina), s.Stamina,
 		"Recovery", renderStat(s.Recovery), s.Recovery,
+		"Weight", renderStat(s.Weight), s.Weight,
 	)
 }
 
@@ -44,6 +54,7 @@ func (s *SnailStats) GenerateStats(level SnailStatLevel) {
 	default:
 		s.generateRandomStats()
 	}
+	s.Weight = randomWeight()
 }
 
 // Starting Snail Stats are randomly generated between 1 and 5 for each stat
@@ -82,11 +93,43 @@ func (s *SnailStats) generateRandomStats() {
 	s.Recovery = randFloat64(1, 20)
 }
 
+func randomWeight() float64 {
+	return randFloat64(MinStat, MaxStat)
+}
+
+// BackfillWeights gives every snail from before weight a random one, they
+// race as if they were weightless until then.
+func BackfillWeights(db *gorm.DB) error {
+	snails := []Snail{}
+	if err := db.Where("weight = 0 OR weight IS NULL").Find(&snails).Error; err != nil {
+		return err
+	}
+
+	for _, snail := range snails {
+		if err := db.Model(&snail).Update("weight", randomWeight()).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(snails) > 0 {
+		log.Infof("Gave %d snails a weight", len(snails))
+	}
+	return nil
+}
+
 func randFloat64(min, max float64) float64 {
 	return rand.Float64()*(max-min) + min
 }
 
+// Render the stat as a bar out of the max stat, stats can go up to 20 so the
+// bar has to be clamped rather than assuming they stop at 10.
 func renderStat(stat float64) string {
 	num := int(stat)
-	return fmt.Sprintf("[%s%s]", strings.Repeat("=", num), strings.Repeat(" ", 10-num))
+	if num < 0 {
+		num = 0
+	}
+	if num > MaxStat {
+		num = MaxStat
+	}
+	return fmt.Sprintf("[%s%s]", strings.Repeat("=", num), strings.Repeat(" ", MaxStat-num))
 }
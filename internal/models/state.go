@@ -1,6 +1,10 @@
 package models
 
 import (
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -8,34 +12,193 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+const (
+	// How long cancelled races get to refund their bets during a shutdown
+	RaceCancelTimeout = 5 * time.Second
+)
+
+var (
+	ErrShuttingDown = fmt.Errorf("shutting down")
+	ErrSnailRacing  = fmt.Errorf("snail is racing")
+)
+
+// State is shared by every interaction handler and race goroutine, the races
+// must only be accessed through its methods.
 type State struct {
-	DB    *gorm.DB
-	Races map[string]*Race
+	DB *gorm.DB
+
+	mu           sync.RWMutex
+	races        map[string]*Race
+	running      sync.WaitGroup
+	shuttingDown bool
 }
 
 func NewState(db *gorm.DB) *State {
 	return &State{
 		DB:    db,
-		Races: make(map[string]*Race, 0),
+		races: make(map[string]*Race, 0),
 	}
 }
 
-func (s *State) NewRace(session *discordgo.Session, channelId string, host *discordgo.User) *Race {
-	// Generate Unique ID
-	id := uuid.New().String()[24:]
-	_, ok := s.Races[id]
-	for ok {
-		id = uuid.New().String()[24:]
-		_, ok = s.Races[id]
+// GetRace gets a race hosted in the guild and channel, races are only ever
+// found from where they were hosted.
+func (s *State) GetRace(guildId string, channelId string, id string) (*Race, bool) {
+	race, ok := s.GetAnyRace(id)
+	if !ok || race.GuildId != guildId || race.ChannelId != channelId {
+		return nil, false
+	}
+	return race, true
+}
+
+// GetAnyRace gets a race from any guild, this is only for the bot admins
+func (s *State) GetAnyRace(id string) (*Race, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	race, ok := s.races[id]
+	return race, ok
+}
+
+// Whether the guild has any races that haven't finished yet
+func (s *State) GuildRacing(guildId string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, race := range s.races {
+		if race.GuildId == guildId {
+			return true
+		}
+	}
+	return false
+}
+
+// Whether any of the snails are in a race that hasn't finished yet
+func (s *State) SnailsRacing(ids ...uint) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, race := range s.races {
+		if race.hasSnail(ids...) {
+			return true
+		}
+	}
+	return false
+}
+
+// WhileIdle runs fn if none of the snails are in a race, none of them can join
+// a race until it returns. Snails that change hands or go into escrow are
+// moved in fn so they can't be racing at the same time.
+func (s *State) WhileIdle(fn func() error, ids ...uint) error {
+	committingSnails.Lock()
+	defer committingSnails.Unlock()
+
+	if s.SnailsRacing(ids...) {
+		return ErrSnailRacing
+	}
+	return fn()
+}
+
+// The races hosted in the guild and channel that are still taking entries
+func (s *State) OpenRaces(guildId string, channelId string) []*Race {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	races := make([]*Race, 0)
+	for _, race := range s.races {
+		if race.GuildId == guildId && race.ChannelId == channelId && race.isOpen() {
+			races = append(races, race)
+		}
+	}
+	return races
+}
+
+func (s *State) removeRace(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.races, id)
+	s.running.Done()
+}
+
+func (s *State) NewRace(session *discordgo.Session, guildId string, channelId string, host *discordgo.User) (*Race, error) {
+	// Generate Unique ID, this also needs to be unique against the race
+	// history so replays are unambiguous. The history is checked before
+	// taking the lock so other races aren't held up by the database.
+	for {
+		race, err := s.addRace(newRaceId(s.DB), guildId, channelId, host)
+		if race != nil || err != nil {
+			return race, err
+		}
+	}
+}
+
+// Add a new race with the id, no race is returned if the id is already taken
+// by a running race
+func (s *State) addRace(id string, guildId string, channelId string, host *discordgo.User) (*Race, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// No new races once we've started shutting down
+	if s.shuttingDown {
+		return nil, ErrShuttingDown
+	}
+	if _, ok := s.races[id]; ok {
+		return nil, nil
 	}
 
 	// Create New Race
 	race := &Race{}
-	race.SetupNewRace(id, channelId, s.DB, host, func() {
-		delete(s.Races, id)
+	race.SetupNewRace(id, guildId, channelId, s.DB, host, func() {
+		s.removeRace(id)
 		log.WithField("race", id).Info("Race is finished")
 	})
-	s.Races[id] = race
+	s.races[id] = race
+	s.running.Add(1)
+
+	return race, nil
+}
 
-	return race
+// Generate a race id that isn't in the race history
+func newRaceId(db *gorm.DB) string {
+	id := uuid.New().String()[24:]
+	for RaceRecordExists(db, id) {
+		id = uuid.New().String()[24:]
+	}
+	return id
+}
+
+// Shutdown stops any new races from being hosted and waits for the running
+// races to finish. Races still running after the timeout are cancelled and
+// their bets refunded.
+func (s *State) Shutdown(timeout time.Duration) {
+	s.mu.Lock()
+	s.shuttingDown = true
+	count := len(s.races)
+	s.mu.Unlock()
+
+	log.Infof("Waiting up to %s for %d races to finish", timeout, count)
+
+	finished := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return
+	case <-time.After(timeout):
+	}
+
+	s.mu.RLock()
+	for _, race := range s.races {
+		race.Cancel("The bot is shutting down.")
+	}
+	s.mu.RUnlock()
+
+	select {
+	case <-finished:
+	case <-time.After(RaceCancelTimeout):
+		log.Warn("Timed out waiting for cancelled races to refund")
+	}
 }
@@ -0,0 +1,422 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type TradeStatus string
+
+const (
+	TradeStatusPending  TradeStatus = "pending"
+	TradeStatusAccepted TradeStatus = "accepted"
+	TradeStatusDenied   TradeStatus = "denied"
+	TradeStatusExpired  TradeStatus = "expired"
+
+	// Trade Constants
+	TradeTimeout   = 10 * time.Minute
+	MaxTradeSnails = 10
+
+	// Action Ids
+	TradeActionAccept = "trade_accept"
+	TradeActionDeny   = "trade_deny"
+)
+
+var (
+	ErrTradeNotFound = fmt.Errorf("trade not found")
+	ErrTradeClosed   = fmt.Errorf("trade is no longer pending")
+	ErrNotTrader     = fmt.Errorf("user isn't part of the trade")
+	ErrEmptyTrade    = fmt.Errorf("trade has nothing in it")
+)
+
+// Trade is an offer from one user to another. The snails and money being
+// offered are held in escrow from the moment the offer is made, the snails and
+// money asked for are only taken when the offer is accepted.
+type Trade struct {
+	gorm.Model
+
+	TradeID   string `gorm:"uniqueIndex"`
+	ChannelID string
+	MessageID string
+
+	FromID    string `gorm:"index"`
+	ToID      string `gorm:"index"`
+	FromMoney int64
+	ToMoney   int64
+
+	Status    TradeStatus `gorm:"index"`
+	ExpiresAt time.Time
+
+	Snails []TradeSnail
+}
+
+// TradeSnail is a snail in the trade, the giver is who owned it when the
+// offer was made.
+type TradeSnail struct {
+	gorm.Model
+
+	TradeID uint `gorm:"index"`
+	SnailID uint
+	Snail   Snail
+	GiverID string
+}
+
+func (t *Trade) escrowTag() string {
+	return escrowTag("trade", t.TradeID)
+}
+
+// The ids of the snails the user is giving in the trade
+func (t *Trade) SnailIDs(giverID string) []uint {
+	ids := make([]uint, 0)
+	for _, snail := range t.Snails {
+		if snail.GiverID == giverID {
+			ids = append(ids, snail.SnailID)
+		}
+	}
+	return ids
+}
+
+// CreateTrade makes a new trade offer and puts what is being offered into
+// escrow. The trade is only saved if everything offered could be held.
+func CreateTrade(db *gorm.DB, from User, to User, fromSnails []Snail, fromMoney int64, toSnails []Snail, toMoney int64) (*Trade, error) {
+	log.Debugf("CreateTrade(from: %s, to: %s)", from.DiscordID, to.DiscordID)
+
+	if len(fromSnails) == 0 && len(toSnails) == 0 && fromMoney == 0 && toMoney == 0 {
+		return nil, ErrEmptyTrade
+	}
+	if fromMoney < 0 || toMoney < 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	trade := &Trade{
+		FromID:    from.DiscordID,
+		ToID:      to.DiscordID,
+		FromMoney: fromMoney,
+		ToMoney:   toMoney,
+		Status:    TradeStatusPending,
+		ExpiresAt: time.Now().Add(TradeTimeout),
+		Snails:    make([]TradeSnail, 0),
+	}
+	for _, snail := range fromSnails {
+		trade.Snails = append(trade.Snails, TradeSnail{SnailID: snail.ID, GiverID: from.DiscordID})
+	}
+	for _, snail := range toSnails {
+		trade.Snails = append(trade.Snails, TradeSnail{SnailID: snail.ID, GiverID: to.DiscordID})
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		trade.TradeID = uuid.New().String()[24:]
+
+		keeps, err := keepsASnail(tx, from.DiscordID, len(fromSnails), len(toSnails))
+		if err != nil {
+			return err
+		}
+		if !keeps {
+			return ErrLastSnail
+		}
+
+		if err := escrowSnails(tx, from.DiscordID, trade.SnailIDs(from.DiscordID), trade.escrowTag()); err != nil {
+			return err
+		}
+
+		if fromMoney > 0 {
+			memo := fmt.Sprintf("Held for trade %s", trade.TradeID)
+			if _, err := Debit(tx, from.DiscordID, fromMoney, LedgerEscrow, memo); err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(trade).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return GetTrade(db, trade.TradeID)
+}
+
+func GetTrade(db *gorm.DB, tradeId string) (*Trade, error) {
+	log.Debugf("GetTrade(trade: %s)", tradeId)
+
+	trade := &Trade{}
+	result := db.Where("trade_id = ?", tradeId).
+		Preload("Snails.Snail", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		First(trade)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, ErrTradeNotFound
+	}
+	return trade, result.Error
+}
+
+// The trades still waiting on the user to accept or deny them
+func GetPendingTrades(db *gorm.DB, discordID string) ([]Trade, error) {
+	trades := []Trade{}
+	result := db.Where("status = ? AND (from_id = ? OR to_id = ?)", TradeStatusPending, discordID, discordID).
+		Preload("Snails").
+		Order("created_at desc").
+		Find(&trades)
+	return trades, result.Error
+}
+
+// Move the trade on from pending, this only works once so a trade can't be
+// both accepted and denied.
+func (t *Trade) close(tx *gorm.DB, status TradeStatus) error {
+	query := tx.Model(&Trade{}).Where("id = ? AND status = ?", t.ID, TradeStatusPending)
+	if status == TradeStatusAccepted {
+		query = query.Where("expires_at > ?", time.Now())
+	}
+
+	result := query.Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTradeClosed
+	}
+
+	t.Status = status
+	return nil
+}
+
+// AcceptTrade swaps everything in the trade in a single transaction. If the
+// user being offered the trade can't hold up their side it stays pending.
+func AcceptTrade(db *gorm.DB, trade *Trade, discordID string) error {
+	log.Debugf("AcceptTrade(trade: %s, user: %s)", trade.TradeID, discordID)
+
+	if discordID != trade.ToID {
+		return ErrNotTrader
+	}
+
+	fromSnails, toSnails := trade.SnailIDs(trade.FromID), trade.SnailIDs(trade.ToID)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := trade.close(tx, TradeStatusAccepted); err != nil {
+			return err
+		}
+
+		keeps, err := keepsASnail(tx, trade.ToID, len(toSnails), len(fromSnails))
+		if err != nil {
+			return err
+		}
+		if !keeps {
+			return ErrLastSnail
+		}
+
+		// Take the other side of the trade
+		if err := escrowSnails(tx, trade.ToID, toSnails, trade.escrowTag()); err != nil {
+			return err
+		}
+		memo := fmt.Sprintf("Trade %s", trade.TradeID)
+		if trade.ToMoney > 0 {
+			if _, err := Debit(tx, trade.ToID, trade.ToMoney, LedgerTrade, memo); err != nil {
+				return err
+			}
+		}
+
+		// Then hand everything over
+		if err := transferSnails(tx, fromSnails, trade.escrowTag(), trade.ToID); err != nil {
+			return err
+		}
+		if err := transferSnails(tx, toSnails, trade.escrowTag(), trade.FromID); err != nil {
+			return err
+		}
+		if trade.FromMoney > 0 {
+			if _, err := Credit(tx, trade.ToID, trade.FromMoney, LedgerTrade, memo); err != nil {
+				return err
+			}
+		}
+		if trade.ToMoney > 0 {
+			if _, err := Credit(tx, trade.FromID, trade.ToMoney, LedgerTrade, memo); err != nil {
+				return err
+			}
+		}
+
+		if err := ensureActiveSnail(tx, trade.FromID); err != nil {
+			return err
+		}
+		return ensureActiveSnail(tx, trade.ToID)
+	})
+}
+
+// DenyTrade turns down the trade, or withdraws it if it's the user that made
+// the offer, and gives back everything held in escrow.
+func DenyTrade(db *gorm.DB, trade *Trade, discordID string) error {
+	log.Debugf("DenyTrade(trade: %s, user: %s)", trade.TradeID, discordID)
+
+	if discordID != trade.ToID && discordID != trade.FromID {
+		return ErrNotTrader
+	}
+	return trade.cancel(db, TradeStatusDenied)
+}
+
+// ExpireTrade gives back everything held for a trade nobody answered in time
+func ExpireTrade(db *gorm.DB, trade *Trade) error {
+	log.Debugf("ExpireTrade(trade: %s)", trade.TradeID)
+	return trade.cancel(db, TradeStatusExpired)
+}
+
+func (t *Trade) cancel(db *gorm.DB, status TradeStatus) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := t.close(tx, status); err != nil {
+			return err
+		}
+
+		if err := releaseSnails(tx, t.escrowTag()); err != nil {
+			return err
+		}
+		if t.FromMoney > 0 {
+			memo := fmt.Sprintf("Returned from trade %s", t.TradeID)
+			if _, err := Credit(tx, t.FromID, t.FromMoney, LedgerEscrow, memo); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PostTrade sends the trade offer to the channel with buttons to accept or
+// deny it, and then watches for the offer to expire.
+func PostTrade(s *discordgo.Session, db *gorm.DB, trade *Trade, channelId string) error {
+	message, err := s.ChannelMessageSendComplex(channelId, &discordgo.MessageSend{
+		Content:    Mention(trade.ToID),
+		Embeds:     []*discordgo.MessageEmbed{trade.embed()},
+		Components: trade.components(),
+	})
+	if err != nil {
+		return err
+	}
+
+	trade.ChannelID, trade.MessageID = channelId, message.ID
+	if err := db.Model(trade).Updates(map[string]interface{}{"channel_id": channelId, "message_id": message.ID}).Error; err != nil {
+		return err
+	}
+
+	goBackground(func(ctx context.Context) { WatchTrade(ctx, s, db, trade.TradeID) })
+	return nil
+}
+
+// WatchTrade waits for the trade to expire, if nobody has answered it by then
+// everything in escrow is given back. A stopped watch leaves the trade
+// pending for the next start.
+func WatchTrade(ctx context.Context, s *discordgo.Session, db *gorm.DB, tradeId string) {
+	trade, err := GetTrade(db, tradeId)
+	if err != nil {
+		log.WithField("trade", tradeId).WithError(err).Warn("Failed to get trade to watch")
+		return
+	}
+
+	if !sleepBackground(ctx, time.Until(trade.ExpiresAt)) {
+		return
+	}
+
+	switch err := ExpireTrade(db, trade); err {
+	case nil:
+		log.WithField("trade", tradeId).Info("Trade expired")
+		trade.Render(s)
+	case ErrTradeClosed:
+	default:
+		log.WithField("trade", tradeId).WithError(err).Error("Failed to expire trade")
+	}
+}
+
+// ResumeTrades watches the trades that were still pending when the bot
+// stopped, any that expired in the meantime are expired straight away.
+func ResumeTrades(s *discordgo.Session, db *gorm.DB) {
+	trades := []Trade{}
+	if err := db.Where("status = ?", TradeStatusPending).Find(&trades).Error; err != nil {
+		log.WithError(err).Error("Failed to find pending trades")
+		return
+	}
+
+	for _, trade := range trades {
+		tradeId := trade.TradeID
+		goBackground(func(ctx context.Context) { WatchTrade(ctx, s, db, tradeId) })
+	}
+}
+
+// Render the trade message as it currently stands
+func (t *Trade) Render(s *discordgo.Session) {
+	if t.MessageID == "" {
+		return
+	}
+
+	edit := discordgo.NewMessageEdit(t.ChannelID, t.MessageID)
+	edit.Embeds = []*discordgo.MessageEmbed{t.embed()}
+	edit.Components = t.components()
+	if _, err := s.ChannelMessageEditComplex(edit); err != nil {
+		log.WithField("trade", t.TradeID).WithError(err).Warn("Failed to render trade")
+	}
+}
+
+func (t *Trade) embed() *discordgo.MessageEmbed {
+	body := fmt.Sprintf("%s has offered %s a trade.\n\n", Mention(t.FromID), Mention(t.ToID))
+	body += fmt.Sprintf("**%s gives:**\n%s\n", Mention(t.FromID), t.renderSide(t.FromID, t.FromMoney))
+	body += fmt.Sprintf("**%s gives:**\n%s\n", Mention(t.ToID), t.renderSide(t.ToID, t.ToMoney))
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("Trade Offer `%s`", t.TradeID),
+		Color: 0x3498db,
+	}
+
+	switch t.Status {
+	case TradeStatusPending:
+		body += fmt.Sprintf("This offer expires <t:%d:R>. Accept or deny it below, or via command:\n```\n/snailrace trade accept trade_id: %s\n```", t.ExpiresAt.Unix(), t.TradeID)
+	case TradeStatusAccepted:
+		body += "The trade has been accepted, enjoy your new things!"
+		embed.Color = 0x2ecc71
+	case TradeStatusDenied:
+		body += "The trade has been called off, everything has been given back."
+		embed.Color = 0xe74c3c
+	case TradeStatusExpired:
+		body += "Nobody answered in time so the trade has expired, everything has been given back."
+		embed.Color = 0xe74c3c
+	}
+
+	embed.Description = body
+	return embed
+}
+
+func (t *Trade) renderSide(giverID string, money int64) string {
+	side := ""
+	for _, snail := range t.Snails {
+		if snail.GiverID == giverID {
+			side += fmt.Sprintf("- 🐌 %s (lvl. %d)\n", snail.Snail.Name, snail.Snail.Level)
+		}
+	}
+	if money > 0 {
+		side += fmt.Sprintf("- 💰 %dg\n", money)
+	}
+	if side == "" {
+		side = "- Nothing\n"
+	}
+	return side
+}
+
+func (t *Trade) components() []discordgo.MessageComponent {
+	if t.Status != TradeStatusPending {
+		return []discordgo.MessageComponent{}
+	}
+
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Accept",
+					Style:    discordgo.SuccessButton,
+					CustomID: fmt.Sprintf("%s:%s", TradeActionAccept, t.TradeID),
+				},
+				discordgo.Button{
+					Label:    "Deny",
+					Style:    discordgo.DangerButton,
+					CustomID: fmt.Sprintf("%s:%s", TradeActionDeny, t.TradeID),
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newTestOwnedSnail(t *testing.T, db *gorm.DB, owner string) Snail {
+	snail := Snail{Name: "snail", OwnerID: owner, Level: 1}
+	if err := db.Create(&snail).Error; err != nil {
+		t.Fatal(err)
+	}
+	return snail
+}
+
+func TestAcceptTradeSwapsEverything(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "from", 100)
+	newTestUser(t, db, "to", 100)
+	from, _ := GetUserByDiscordID(db, "from")
+	to, _ := GetUserByDiscordID(db, "to")
+	fromSnails := []Snail{newTestOwnedSnail(t, db, "from"), newTestOwnedSnail(t, db, "from")}
+	toSnails := []Snail{newTestOwnedSnail(t, db, "to")}
+
+	trade, err := CreateTrade(db, *from, *to, fromSnails[:1], 30, toSnails, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The offered snail is held and can't be offered again
+	if _, err := CreateTrade(db, *from, *to, fromSnails[:1], 0, nil, 0); err != ErrSnailInEscrow {
+		t.Errorf("offered a snail held in escrow, got %v", err)
+	}
+
+	// Only the user the trade was offered to can accept it
+	if err := AcceptTrade(db, trade, "from"); err != ErrNotTrader {
+		t.Errorf("offerer accepted their own trade, got %v", err)
+	}
+	if err := AcceptTrade(db, trade, "to"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DenyTrade(db, trade, "to"); err != ErrTradeClosed {
+		t.Errorf("denied an accepted trade, got %v", err)
+	}
+
+	for id, owner := range map[uint]string{fromSnails[0].ID: "to", toSnails[0].ID: "from"} {
+		var snail Snail
+		db.First(&snail, id)
+		if snail.OwnerID != owner || snail.InEscrow() {
+			t.Errorf("traded snail is owned by %q with escrow %q", snail.OwnerID, snail.Escrow)
+		}
+	}
+
+	from, _ = GetUserByDiscordID(db, "from")
+	to, _ = GetUserByDiscordID(db, "to")
+	if from.Money != to.Money-40 {
+		t.Errorf("balances are %dg and %dg after trading 30g for 10g", from.Money, to.Money)
+	}
+}
+
+func TestDenyTradeReturnsEscrow(t *testing.T) {
+	db := newTestDB(t)
+	newTestUser(t, db, "from", 100)
+	newTestUser(t, db, "to", 100)
+	from, _ := GetUserByDiscordID(db, "from")
+	to, _ := GetUserByDiscordID(db, "to")
+	snails := []Snail{newTestOwnedSnail(t, db, "from"), newTestOwnedSnail(t, db, "from")}
+
+	trade, err := CreateTrade(db, *from, *to, snails[:1], 50, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DenyTrade(db, trade, "from"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AcceptTrade(db, trade, "to"); err != ErrTradeClosed {
+		t.Errorf("accepted a denied trade, got %v", err)
+	}
+
+	var snail Snail
+	db.First(&snail, snails[0].ID)
+	if snail.OwnerID != "from" || snail.InEscrow() {
+		t.Errorf("returned snail is owned by %q with escrow %q", snail.OwnerID, snail.Escrow)
+	}
+
+	var held int64
+	db.Model(&LedgerEntry{}).Where("kind = ?", LedgerEscrow).Select("sum(amount)").Scan(&held)
+	if held != 0 {
+		t.Errorf("%dg is still held after the trade was denied", held)
+	}
+}
@@ -16,7 +16,8 @@ type User struct {
 	Races uint64 `gorm:"default:0"`
 	Wins  uint64 `gorm:"default:0"`
 
-	Money uint64 `gorm:"default:10"`
+	// Money must only be changed through the ledger, see ApplyLedgerEntry
+	Money int64 `gorm:"default:10"`
 }
 
 func GetUserByDiscordID(db *gorm.DB, discordID string) (*User, error) {
@@ -30,7 +31,11 @@ func GetUserByDiscordID(db *gorm.DB, discordID string) (*User, error) {
 func GetPercentageLevelProgress(db *gorm.DB, user *User) float64 {
 	log.Debugf("GetPercentageLevelProgress(user: %s)", user.DiscordID)
 
+	// Users start at level 0, which has no threshold to make progress on
 	threshold := user.Level * 100
+	if threshold == 0 {
+		return 0
+	}
 	percentage := (user.XP * 100 / threshold)
 	return float64(percentage)
 }
@@ -43,21 +48,6 @@ func CreateUser(db *gorm.DB, discordID string) (*User, error) {
 	return user, result.Error
 }
 
-func (user *User) RemoveMoney(db *gorm.DB, amount uint64) error {
-	log.Debugf("RemoveMoney(id: %s, amount: %d)", user.DiscordID, amount)
-
-	user.Money -= amount
-	result := db.Save(user)
-	return result.Error
-}
-
-func (user *User) AddMoney(db *gorm.DB, amount uint64) error {
-	log.Debugf("AddMoney(id: %s, amount: %d)", user.DiscordID, amount)
-
-	user.Money += amount
-	result := db.Save(user)
-	return result.Error
-}
 func (user *User) AddXP(db *gorm.DB, amount uint64) error {
 	log.Debugf("AddXP(id: %s, amount: %d)", user.DiscordID, amount)
 
@@ -67,7 +57,8 @@ func (user *User) AddXP(db *gorm.DB, amount uint64) error {
 		user.Level++
 	}
 
-	result := db.Save(user)
+	// Only save the columns we changed, so we don't clobber the balance
+	result := db.Model(user).Select("XP", "Level").Updates(user)
 	return result.Error
 }
 
@@ -79,6 +70,6 @@ func (user *User) AddRace(db *gorm.DB, win bool) error {
 		user.Wins++
 	}
 
-	result := db.Save(user)
+	result := db.Model(user).Select("Races", "Wins").Updates(user)
 	return result.Error
 }
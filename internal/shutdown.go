@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/lcox74/snailrace/internal/models"
+
+	"github.com/bwmarrin/discordgo"
+	"gorm.io/gorm"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	ShutdownTimeoutEnv     = "SHUTDOWN_TIMEOUT"
+	DefaultShutdownTimeout = 90 * time.Second
+)
+
+// The time running races get to finish during a shutdown, configured in
+// seconds. The container needs a longer stop grace period than this.
+func shutdownTimeout() time.Duration {
+	value := os.Getenv(ShutdownTimeoutEnv)
+	if value == "" {
+		return DefaultShutdownTimeout
+	}
+
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		log.WithField("env", ShutdownTimeoutEnv).Warnf("Invalid shutdown timeout %q, using the default", value)
+		return DefaultShutdownTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Shutdown drains the running races and stops the background timers, then
+// closes the Discord session and the database. New races are refused while
+// this is happening.
+func Shutdown(state *models.State, discord *discordgo.Session, db *gorm.DB) {
+	log.Infoln("Shutting down, no new races will be hosted.")
+	state.Shutdown(shutdownTimeout())
+
+	// The trade, auction, hype and raffle timers use the session and database
+	models.StopBackground()
+
+	if err := discord.Close(); err != nil {
+		log.WithError(err).Warn("Failed closing Discord session")
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.WithError(err).Warn("Failed getting database connection")
+		return
+	}
+	if err := sqlDB.Close(); err != nil {
+		log.WithError(err).Warn("Failed closing database")
+	}
+}
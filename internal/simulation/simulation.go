@@ -0,0 +1,263 @@
+// Package simulation runs snail races without any knowledge of Discord, the
+// database or the wall clock. Given a seed and the entrants it will always
+// produce the same race, which means a race can be replayed, tested and audited
+// after the fact.
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+const (
+	MaxStep         float64 = 5.0
+	BaseTrackLength float64 = 100.0
+
+	// Safety net so a race full of exhausted snails can't run forever, this
+	// is for a base length track and scales with the track length.
+	MaxFrames = 1000
+
+	// Heavier snails cover less ground when they sprint but dig in against
+	// sliding back. These are how much of its sprint a 20 weight snail loses
+	// and how much of the backslide it shrugs off, a weightless snail races as
+	// if weight didn't exist.
+	WeightDrag = 0.1
+	WeightGrip = 0.5
+)
+
+// Entrant is everything the simulation needs to know about a snail
+type Entrant struct {
+	Speed    float64 `json:"speed"`
+	Stamina  float64 `json:"stamina"`
+	Recovery float64 `json:"recovery"`
+	Weight   float64 `json:"weight"`
+	Mood     float64 `json:"mood"`
+}
+
+// Frame is a snapshot of every entrant after a single step of the race, the
+// slices are indexed the same as the entrants given to Run.
+type Frame struct {
+	Positions []float64 `json:"positions"`
+	Stamina   []float64 `json:"stamina"`
+}
+
+// Placing records when an entrant crossed the line and where it placed. Snails
+// that cross on the same frame share the same position.
+type Placing struct {
+	Entrant  int `json:"entrant"`
+	Position int `json:"position"`
+	Frame    int `json:"frame"`
+}
+
+type Result struct {
+	Seed     int64     `json:"seed"`
+	Length   float64   `json:"length"`
+	Frames   []Frame   `json:"frames"`
+	Placings []Placing `json:"placings"`
+}
+
+type runner struct {
+	Entrant
+	position float64
+	stamina  float64
+	resting  bool
+}
+
+// Run simulates a full race over the track length from the seed and entrants,
+// returning the timeline of every frame and the finishing order.
+func Run(seed int64, length float64, entrants []Entrant) *Result {
+	rng := rand.New(rand.NewSource(seed))
+	result := &Result{
+		Seed:     seed,
+		Length:   length,
+		Frames:   make([]Frame, 0),
+		Placings: make([]Placing, 0),
+	}
+
+	runners := make([]*runner, len(entrants))
+	for index, entrant := range entrants {
+		runners[index] = &runner{Entrant: entrant, stamina: entrant.Stamina}
+	}
+
+	// Race until all snails have finished
+	finished := make([]bool, len(runners))
+	snailsFinished := 0
+	maxFrames := int(float64(MaxFrames) * length / BaseTrackLength)
+	for frame := 0; snailsFinished < len(runners) && frame < maxFrames; frame++ {
+		for index, r := range runners {
+			if finished[index] {
+				continue
+			}
+
+			// Step snail forward
+			r.step(rng, length)
+			if r.position >= length {
+				finished[index] = true
+				snailsFinished++
+				result.place(index, frame)
+			}
+		}
+		result.Frames = append(result.Frames, snapshot(runners))
+	}
+
+	result.placeUnfinished(runners, finished)
+	return result
+}
+
+// Step calculates the next step for the snail, based on the snail's stats and
+// mood. This is still in testing stages and will probably be changed depending
+// on how the game feels.
+func (r *runner) step(rng *rand.Rand, length float64) {
+	// Generate Random Bias
+	bias := rng.Float64() + r.Mood
+
+	// Calculate max next step, any snail with a speed of 4 or more will
+	// always take one while it has stamina
+	maxStepPotential := (r.Speed / 20.0 * MaxStep)
+
+	if r.stamina > 0.0 && !r.resting {
+		if bias >= (1.0 - maxStepPotential) {
+			r.position += SprintStep(r.Weight)
+			r.stamina -= rng.Float64() * 2.0 * sprintCost(length)
+		} else {
+			r.position += float64(rng.Intn(int(MaxStep)))
+			r.stamina -= rng.Float64()
+		}
+
+		r.position -= rng.Float64() * Backslide(r.Weight)
+	} else {
+		r.stamina += recoveryRate(r.Recovery, length)
+		r.resting = r.stamina < r.Stamina*restFraction(length)
+	}
+
+	// Make sure the snail doesn't go out of bounds
+	r.position = math.Min(r.position, length)
+	r.stamina = math.Max(0.0, r.stamina)
+}
+
+// SprintStep is how far a snail gets when it takes a max step, heavier snails
+// don't get as far.
+func SprintStep(weight float64) float64 {
+	return MaxStep * (1.0 - WeightDrag*weight/20.0)
+}
+
+// Backslide is how much of the random slide back the snail takes, heavier
+// snails slide back less.
+func Backslide(weight float64) float64 {
+	return 1.0 - WeightGrip*weight/20.0
+}
+
+// Longer races make an exhausted snail rest until it has won back part of its
+// stamina before moving again. On a base length track a snail gets going as
+// soon as it has any stamina, so sprints favour speed while longer races
+// favour stamina and recovery.
+func restFraction(length float64) float64 {
+	return math.Max(0.0, math.Min(0.5, (length/BaseTrackLength-1.0)*0.1))
+}
+
+// Sprinting the whole way is fine for a short race, but the further the track
+// the more each max step takes out of the snail.
+func sprintCost(length float64) float64 {
+	return math.Sqrt(length / BaseTrackLength)
+}
+
+// Stamina recovered per frame while resting, this grows with the track length
+// so a long race isn't spent entirely sitting still.
+func recoveryRate(recovery float64, length float64) float64 {
+	return recovery / 10.0 * math.Sqrt(length/BaseTrackLength)
+}
+
+func snapshot(runners []*runner) Frame {
+	frame := Frame{
+		Positions: make([]float64, len(runners)),
+		Stamina:   make([]float64, len(runners)),
+	}
+	for index, r := range runners {
+		frame.Positions[index] = r.position
+		frame.Stamina[index] = r.stamina
+	}
+	return frame
+}
+
+// When a snail crosses the line, add it to the placings. If there is already a
+// snail with the same frame, then the snail is tied with the other snail.
+func (res *Result) place(entrant int, frame int) {
+	pos := 1
+	for _, p := range res.Placings {
+		if p.Position >= pos {
+			pos = p.Position + 1
+		}
+
+		// Check for a possible tie
+		if p.Frame == frame {
+			pos = p.Position
+			break
+		}
+	}
+
+	res.Placings = append(res.Placings, Placing{
+		Entrant:  entrant,
+		Position: pos,
+		Frame:    frame,
+	})
+}
+
+// If the race hit MaxFrames then the remaining snails are placed by how far
+// they got, snails on the same spot of the track share a position.
+func (res *Result) placeUnfinished(runners []*runner, finished []bool) {
+	remaining := make([]int, 0)
+	for index := range runners {
+		if !finished[index] {
+			remaining = append(remaining, index)
+		}
+	}
+	if len(remaining) == 0 {
+		return
+	}
+
+	sort.SliceStable(remaining, func(i, j int) bool {
+		return runners[remaining[i]].position > runners[remaining[j]].position
+	})
+
+	pos, lastFrame := 0, len(res.Frames)-1
+	for _, p := range res.Placings {
+		if p.Position > pos {
+			pos = p.Position
+		}
+	}
+	for i, index := range remaining {
+		if i == 0 || runners[index].position != runners[remaining[i-1]].position {
+			pos++
+		}
+		res.Placings = append(res.Placings, Placing{
+			Entrant:  index,
+			Position: pos,
+			Frame:    lastFrame,
+		})
+	}
+}
+
+// Position returns the finishing position of the entrant, or 0 if it didn't
+// place at all.
+func (res Result) Position(entrant int) int {
+	for _, p := range res.Placings {
+		if p.Entrant == entrant {
+			return p.Position
+		}
+	}
+	return 0
+}
+
+// Tie checks the race for a tie, it doesn't matter how many are in the tie,
+// just that there is a tie.
+func (res Result) Tie() bool {
+	seen := make(map[int]bool)
+	for _, p := range res.Placings {
+		if seen[p.Position] {
+			return true
+		}
+		seen[p.Position] = true
+	}
+	return false
+}
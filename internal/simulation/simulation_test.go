@@ -0,0 +1,136 @@
+package simulation
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+var testEntrants = []Entrant{
+	{Speed: 5, Stamina: 5, Recovery: 5},
+	{Speed: 12, Stamina: 8, Recovery: 3},
+	{Speed: 18, Stamina: 2, Recovery: 15},
+	{Speed: 9, Stamina: 14, Recovery: 9, Mood: 0.1},
+}
+
+func TestRunIsDeterministic(t *testing.T) {
+	for _, length := range []float64{BaseTrackLength, BaseTrackLength * 5} {
+		first := Run(42, length, testEntrants)
+		second := Run(42, length, testEntrants)
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("races over %.0f with the same seed and entrants differ", length)
+		}
+		if len(first.Placings) != len(testEntrants) {
+			t.Errorf("race over %.0f placed %d of %d entrants", length, len(first.Placings), len(testEntrants))
+		}
+
+		other := Run(43, length, testEntrants)
+		if reflect.DeepEqual(first.Frames, other.Frames) {
+			t.Errorf("races over %.0f with different seeds have the same frames", length)
+		}
+	}
+}
+
+func TestPlaceTies(t *testing.T) {
+	res := &Result{}
+	res.place(0, 10)
+	res.place(1, 10)
+	res.place(2, 12)
+
+	for entrant, want := range []int{1, 1, 2} {
+		if got := res.Position(entrant); got != want {
+			t.Errorf("entrant %d placed %d, want %d", entrant, got, want)
+		}
+	}
+	if !res.Tie() {
+		t.Error("tied finish wasn't reported as a tie")
+	}
+	if res.Position(3) != 0 {
+		t.Error("entrant that didn't race has a position")
+	}
+}
+
+func TestUnfinishedSnailsPlacedByDistance(t *testing.T) {
+	// Snails without stamina or recovery never get anywhere, and a snail with
+	// a little stamina and no recovery gives up part way round
+	entrants := []Entrant{
+		{Speed: 20, Stamina: 20, Recovery: 20},
+		{},
+		{Speed: 20, Stamina: 3},
+		{},
+	}
+	res := Run(7, BaseTrackLength, entrants)
+
+	if len(res.Frames) != MaxFrames {
+		t.Fatalf("race ran for %d frames, want %d", len(res.Frames), MaxFrames)
+	}
+	if res.Position(0) != 1 {
+		t.Errorf("only finisher placed %d", res.Position(0))
+	}
+	if res.Position(1) != res.Position(3) {
+		t.Errorf("snails that never moved placed %d and %d", res.Position(1), res.Position(3))
+	}
+
+	// The further a snail got the better it places
+	last := res.Frames[len(res.Frames)-1]
+	unfinished := []int{1, 2, 3}
+	sort.Slice(unfinished, func(i, j int) bool {
+		return last.Positions[unfinished[i]] > last.Positions[unfinished[j]]
+	})
+	for i := 1; i < len(unfinished); i++ {
+		ahead, behind := unfinished[i-1], unfinished[i]
+		if last.Positions[ahead] > last.Positions[behind] && res.Position(ahead) >= res.Position(behind) {
+			t.Errorf("entrant %d got further than %d but placed %d to %d", ahead, behind, res.Position(ahead), res.Position(behind))
+		}
+	}
+	for index := range entrants {
+		if res.Position(index) == 0 {
+			t.Errorf("entrant %d wasn't placed", index)
+		}
+	}
+}
+
+// The step a weightless snail took on a 1m track before race lengths were
+// added, the default race should still play out the same.
+func baselineStep(r *runner, rng *rand.Rand) {
+	bias := rng.Float64() + r.Mood
+	maxStepPotential := (r.Speed / 20.0 * MaxStep)
+
+	if r.stamina > 0.0 {
+		if bias >= (1.0 - maxStepPotential) {
+			r.position += MaxStep
+			r.stamina -= rng.Float64() * 2.0
+		} else {
+			r.position += float64(rng.Intn(int(MaxStep)))
+			r.stamina -= rng.Float64()
+		}
+
+		r.position -= rng.Float64()
+	} else {
+		r.stamina += r.Recovery / 10.0
+	}
+
+	r.position = math.Min(r.position, BaseTrackLength)
+	r.stamina = math.Max(0.0, r.stamina)
+}
+
+func TestDefaultRaceMatchesBaseline(t *testing.T) {
+	entrants := append([]Entrant{{Speed: 2, Stamina: 1, Recovery: 1}}, testEntrants...)
+	for seed := int64(0); seed < 10; seed++ {
+		rng, baselineRng := rand.New(rand.NewSource(seed)), rand.New(rand.NewSource(seed))
+		for index, entrant := range entrants {
+			r := &runner{Entrant: entrant, stamina: entrant.Stamina}
+			baseline := &runner{Entrant: entrant, stamina: entrant.Stamina}
+			for frame := 0; frame < MaxFrames && baseline.position < BaseTrackLength; frame++ {
+				r.step(rng, BaseTrackLength)
+				baselineStep(baseline, baselineRng)
+				if r.position != baseline.position || r.stamina != baseline.stamina {
+					t.Fatalf("seed %d entrant %d frame %d is at %.2f with %.2f stamina, want %.2f with %.2f",
+						seed, index, frame, r.position, r.stamina, baseline.position, baseline.stamina)
+				}
+			}
+		}
+	}
+}
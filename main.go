@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/lcox74/snailrace/internal"
 	"github.com/lcox74/snailrace/internal/models"
@@ -40,11 +41,16 @@ func main() {
 		return
 	}
 
-	// Wait until CTRL-C or other term signal is received.
+	// Wait until CTRL-C or other term signal is received, Docker stops the
+	// container with SIGTERM.
 	log.Infoln("Snail racer is now running.")
 	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	<-c
+
+	// Let the running races finish before closing everything down
+	internal.Shutdown(state, discord, db)
+	log.Infoln("Snail racer has shut down.")
 }
 
 func setupLogging() {